@@ -0,0 +1,51 @@
+package cable
+
+import (
+	"context"
+	"net/http"
+)
+
+// AttributeKey identifies a request attribute of type T. Keys are compared by identity,
+// so attributes of different packages never collide.
+type AttributeKey[T any] struct {
+	name string
+}
+
+// NewAttributeKey creates a key, name is used for debugging only
+func NewAttributeKey[T any](name string) *AttributeKey[T] {
+	return &AttributeKey[T]{name: name}
+}
+
+func (k *AttributeKey[T]) String() string {
+	return k.name
+}
+
+// SetAttribute stores a value for the rest of the request, e.g. a tenant resolved by a filter
+func SetAttribute[T any](request *http.Request, key *AttributeKey[T], value T) {
+	scope := scopeOf(request)
+	scope.mutex.Lock()
+	defer scope.mutex.Unlock()
+
+	if scope.attributes == nil {
+		scope.attributes = map[interface{}]interface{}{}
+	}
+
+	scope.attributes[key] = value
+}
+
+// Attribute returns the value stored for key and false if there is none
+func Attribute[T any](request *http.Request, key *AttributeKey[T]) (T, bool) {
+	scope := scopeOf(request)
+	scope.mutex.Lock()
+	defer scope.mutex.Unlock()
+
+	value, ok := scope.attributes[key].(T)
+
+	return value, ok
+}
+
+// Context is cancelled when the client disconnects or a timeout interceptor expires.
+// It carries the request scoped attributes.
+func (r RequestEntity) Context() context.Context {
+	return r.Request.Context()
+}
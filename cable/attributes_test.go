@@ -0,0 +1,72 @@
+package cable
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	assert "github.com/stfsy/golang-assert"
+)
+
+var tenantKey = NewAttributeKey[string]("tenant")
+
+func TestAttributesAreHandedFromFiltersToHandlers(t *testing.T) {
+	reset()
+	Filter("/.*", func(writer http.ResponseWriter, request *http.Request) {
+		SetAttribute(request, tenantKey, request.Header.Get("X-Tenant"))
+	})
+	Get("/persons", func(req RequestEntity, resp *ResponseEntity) {
+		tenant, _ := Attribute(req.Request, tenantKey)
+		resp.Status = 200
+		resp.Body = []byte(tenant)
+	})
+
+	request := httptest.NewRequest(http.MethodGet, "/persons", strings.NewReader(""))
+	request.Header.Set("X-Tenant", "mushroom-kingdom")
+	recorder := httptest.NewRecorder()
+
+	HandleRequest(recorder, request)
+
+	assert.Equal(t, recorder.Body.String(), "mushroom-kingdom", "Handler reads the attribute")
+}
+
+func TestAttributeKeysAreDistinct(t *testing.T) {
+	request := withRequestScope(httptest.NewRequest(http.MethodGet, "/persons", strings.NewReader("")))
+	otherKey := NewAttributeKey[string]("tenant")
+	countKey := NewAttributeKey[int]("count")
+
+	SetAttribute(request, tenantKey, "mushroom-kingdom")
+	SetAttribute(request, countKey, 42)
+
+	_, other := Attribute(request, otherKey)
+	count, _ := Attribute(request, countKey)
+
+	assert.Equal(t, other, false, "Keys with equal names are distinct")
+	assert.Equal(t, count, 42, "Values are typed")
+}
+
+func TestContextIsCancelledWithClientConnection(t *testing.T) {
+	reset()
+	cancelled := make(chan bool, 1)
+	Get("/persons", func(req RequestEntity, resp *ResponseEntity) {
+		select {
+		case <-req.Context().Done():
+			cancelled <- true
+		case <-time.After(time.Second):
+			cancelled <- false
+		}
+
+		resp.Status = 200
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	request := httptest.NewRequest(http.MethodGet, "/persons", strings.NewReader("")).WithContext(ctx)
+	cancel()
+
+	HandleRequest(httptest.NewRecorder(), request)
+
+	assert.Equal(t, <-cancelled, true, "Handler observes cancellation")
+}
@@ -0,0 +1,377 @@
+package cable
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+const (
+	authorizationHeader   = "Authorization"
+	wwwAuthenticateHeader = "WWW-Authenticate"
+)
+
+var (
+	// ErrInvalidCredentials is returned by authenticators if credentials are present but wrong
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+// Principal is the authenticated caller of a request
+type Principal struct {
+	Name string
+	// Scheme is the authentication scheme that resolved the principal, e.g. Basic
+	Scheme      string
+	Roles       []string
+	Scopes      []string
+	Permissions []string
+	// Claims of the token the principal was resolved from, if any
+	Claims map[string]interface{}
+}
+
+// Authenticator resolves the principal of a request for one authentication scheme
+type Authenticator interface {
+	// Authenticate returns nil, nil if the request carries no credentials for this scheme
+	Authenticate(request *http.Request) (*Principal, error)
+	// Challenge is sent as WWW-Authenticate header if authentication failed
+	Challenge(err error) string
+}
+
+type Authentication struct {
+	Authenticators []Authenticator
+	public         []*regexp.Regexp
+}
+
+// NewAuthentication tries the given authenticators in order. Register it with
+// cable.Filter(pattern, authentication.Filter).
+func NewAuthentication(authenticators ...Authenticator) *Authentication {
+	return &Authentication{Authenticators: authenticators}
+}
+
+// Public marks routes that do not require authentication. Credentials sent to public
+// routes are still resolved, but failing to authenticate does not reject the request.
+func (a *Authentication) Public(patterns ...string) *Authentication {
+	for _, pattern := range patterns {
+		a.public = append(a.public, stringToRegex(pattern))
+	}
+
+	return a
+}
+
+func (a *Authentication) Filter(writer http.ResponseWriter, request *http.Request) {
+	public := a.isPublic(request.URL.Path)
+
+	for _, authenticator := range a.Authenticators {
+		principal, err := authenticator.Authenticate(request)
+
+		if err != nil {
+			if public {
+				continue
+			}
+
+			logger.Info("Authentication failed",
+				zap.String("Path", request.URL.Path),
+				zap.Error(err))
+
+			writer.Header().Add(wwwAuthenticateHeader, authenticator.Challenge(err))
+			writeError(writer, request, http.StatusUnauthorized, "credentials are invalid")
+			return
+		}
+
+		if principal != nil {
+			scopeOf(request).principal = principal
+			return
+		}
+	}
+
+	if public {
+		return
+	}
+
+	/*
+	* The server generating a 401 response MUST send a WWW-Authenticate header field
+	* containing at least one challenge applicable to the target resource.
+	*
+	* https://tools.ietf.org/html/rfc7235#section-3.1
+	 */
+	for _, authenticator := range a.Authenticators {
+		writer.Header().Add(wwwAuthenticateHeader, authenticator.Challenge(nil))
+	}
+
+	writeError(writer, request, http.StatusUnauthorized, "authentication is required")
+}
+
+func (a *Authentication) isPublic(path string) bool {
+	for _, pattern := range a.public {
+		if len(pattern.FindString(path)) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// PrincipalOf returns the principal resolved for a request, e.g. for use in filters
+func PrincipalOf(request *http.Request) *Principal {
+	return scopeOf(request).principal
+}
+
+// CredentialStore verifies username and password for basic authentication
+type CredentialStore interface {
+	Verify(username string, password string) (*Principal, error)
+}
+
+type BasicAuthenticator struct {
+	Realm       string
+	Credentials CredentialStore
+}
+
+func NewBasicAuthenticator(realm string, credentials CredentialStore) *BasicAuthenticator {
+	return &BasicAuthenticator{Realm: realm, Credentials: credentials}
+}
+
+func (b *BasicAuthenticator) Authenticate(request *http.Request) (*Principal, error) {
+	if !hasAuthorizationScheme(request, "Basic") {
+		return nil, nil
+	}
+
+	username, password, ok := request.BasicAuth()
+
+	if !ok {
+		return nil, ErrInvalidCredentials
+	}
+
+	principal, err := b.Credentials.Verify(username, password)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if principal == nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	principal.Scheme = "Basic"
+
+	return principal, nil
+}
+
+func (b *BasicAuthenticator) Challenge(err error) string {
+	/*
+	* https://tools.ietf.org/html/rfc7617#section-2.1
+	 */
+	return fmt.Sprintf("Basic realm=%q, charset=\"UTF-8\"", b.Realm)
+}
+
+// TokenVerifier resolves the principal a bearer token was issued for
+type TokenVerifier interface {
+	Verify(token string) (*Principal, error)
+}
+
+// TokenVerifierFunc adapts a function to a TokenVerifier
+type TokenVerifierFunc func(token string) (*Principal, error)
+
+func (f TokenVerifierFunc) Verify(token string) (*Principal, error) {
+	return f(token)
+}
+
+type BearerAuthenticator struct {
+	Realm    string
+	Verifier TokenVerifier
+}
+
+func NewBearerAuthenticator(realm string, verifier TokenVerifier) *BearerAuthenticator {
+	return &BearerAuthenticator{Realm: realm, Verifier: verifier}
+}
+
+func (b *BearerAuthenticator) Authenticate(request *http.Request) (*Principal, error) {
+	if !hasAuthorizationScheme(request, "Bearer") {
+		return nil, nil
+	}
+
+	token := strings.TrimSpace(request.Header.Get(authorizationHeader)[len("Bearer"):])
+
+	if len(token) == 0 {
+		return nil, ErrInvalidCredentials
+	}
+
+	principal, err := b.Verifier.Verify(token)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if principal == nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	principal.Scheme = "Bearer"
+
+	return principal, nil
+}
+
+func (b *BearerAuthenticator) Challenge(err error) string {
+	/*
+	* If the protected resource request included an access token and failed
+	* authentication, the resource server SHOULD include the "error" attribute.
+	*
+	* https://tools.ietf.org/html/rfc6750#section-3
+	 */
+	if err != nil {
+		return fmt.Sprintf("Bearer realm=%q, error=\"invalid_token\"", b.Realm)
+	}
+
+	return fmt.Sprintf("Bearer realm=%q", b.Realm)
+}
+
+// APIKeyStore resolves the principal an api key belongs to
+type APIKeyStore interface {
+	Lookup(key string) (*Principal, error)
+}
+
+type APIKeyAuthenticator struct {
+	Realm      string
+	Header     string
+	QueryParam string
+	Keys       APIKeyStore
+}
+
+// NewAPIKeyAuthenticator reads api keys from the given header and, if not empty, query parameter
+func NewAPIKeyAuthenticator(realm string, header string, queryParam string, keys APIKeyStore) *APIKeyAuthenticator {
+	return &APIKeyAuthenticator{Realm: realm, Header: header, QueryParam: queryParam, Keys: keys}
+}
+
+func (a *APIKeyAuthenticator) Authenticate(request *http.Request) (*Principal, error) {
+	key := request.Header.Get(a.Header)
+
+	if len(key) == 0 && len(a.QueryParam) > 0 {
+		key = request.URL.Query().Get(a.QueryParam)
+	}
+
+	if len(key) == 0 {
+		return nil, nil
+	}
+
+	principal, err := a.Keys.Lookup(key)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if principal == nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	principal.Scheme = "APIKey"
+
+	return principal, nil
+}
+
+func (a *APIKeyAuthenticator) Challenge(err error) string {
+	return fmt.Sprintf("APIKey realm=%q, header=%q", a.Realm, a.Header)
+}
+
+func hasAuthorizationScheme(request *http.Request, scheme string) bool {
+	authorization := request.Header.Get(authorizationHeader)
+
+	/*
+	* It uses a case-insensitive token as a means to identify the authentication scheme
+	*
+	* https://tools.ietf.org/html/rfc7235#section-2.1
+	 */
+	return len(authorization) > len(scheme) &&
+		strings.EqualFold(authorization[:len(scheme)], scheme) &&
+		authorization[len(scheme)] == ' '
+}
+
+type memoryCredentialStore struct {
+	mutex sync.RWMutex
+	users map[string]memoryCredential
+}
+
+type memoryCredential struct {
+	hash      string
+	principal Principal
+}
+
+// MemoryCredentialStore keeps password hashes created with HashPassword in memory
+type MemoryCredentialStore interface {
+	CredentialStore
+	Add(username string, passwordHash string, principal Principal)
+}
+
+func NewMemoryCredentialStore() MemoryCredentialStore {
+	return &memoryCredentialStore{users: map[string]memoryCredential{}}
+}
+
+func (m *memoryCredentialStore) Add(username string, passwordHash string, principal Principal) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if len(principal.Name) == 0 {
+		principal.Name = username
+	}
+
+	m.users[username] = memoryCredential{hash: passwordHash, principal: principal}
+}
+
+func (m *memoryCredentialStore) Verify(username string, password string) (*Principal, error) {
+	m.mutex.RLock()
+	credential, ok := m.users[username]
+	m.mutex.RUnlock()
+
+	if !ok {
+		// unknown users must not be told apart by response time
+		verifyUnknownUser(password)
+		return nil, ErrInvalidCredentials
+	}
+
+	if !VerifyPassword(credential.hash, password) {
+		return nil, ErrInvalidCredentials
+	}
+
+	principal := credential.principal
+
+	return &principal, nil
+}
+
+type memoryAPIKeyStore struct {
+	mutex sync.RWMutex
+	keys  map[[sha256.Size]byte]Principal
+}
+
+// MemoryAPIKeyStore keeps sha256 digests of api keys in memory
+type MemoryAPIKeyStore interface {
+	APIKeyStore
+	Add(key string, principal Principal)
+}
+
+func NewMemoryAPIKeyStore() MemoryAPIKeyStore {
+	return &memoryAPIKeyStore{keys: map[[sha256.Size]byte]Principal{}}
+}
+
+func (m *memoryAPIKeyStore) Add(key string, principal Principal) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.keys[sha256.Sum256([]byte(key))] = principal
+}
+
+func (m *memoryAPIKeyStore) Lookup(key string) (*Principal, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	// looking up the digest does not leak the key through timing
+	principal, ok := m.keys[sha256.Sum256([]byte(key))]
+
+	if !ok {
+		return nil, ErrInvalidCredentials
+	}
+
+	return &principal, nil
+}
@@ -0,0 +1,216 @@
+package cable
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	assert "github.com/stfsy/golang-assert"
+)
+
+func init() {
+	// keep hashing fast in tests
+	passwordHashIterations = 1000
+}
+
+func TestBasicAuthenticationResolvesPrincipal(t *testing.T) {
+	reset()
+	registerAuthentication()
+	registerGetPrincipal()
+
+	request := httptest.NewRequest(http.MethodGet, "/principal", strings.NewReader(""))
+	request.SetBasicAuth("mario", "secret")
+	recorder := httptest.NewRecorder()
+
+	HandleRequest(recorder, request)
+
+	assert.Equal(t, recorder.Code, 200, "Response status code is 200")
+	assert.Equal(t, recorder.Body.String(), "mario Basic", "Response body includes principal and scheme")
+}
+
+func TestBasicAuthenticationWrongPasswordResponds401(t *testing.T) {
+	reset()
+	registerAuthentication()
+	registerGetPrincipal()
+
+	request := httptest.NewRequest(http.MethodGet, "/principal", strings.NewReader(""))
+	request.SetBasicAuth("mario", "wrong")
+	recorder := httptest.NewRecorder()
+
+	HandleRequest(recorder, request)
+
+	assert.Equal(t, recorder.Code, 401, "Response status code is 401")
+	assert.Equal(t, recorder.Header().Get("WWW-Authenticate"), "Basic realm=\"cable\", charset=\"UTF-8\"", "Basic challenge is sent")
+}
+
+func TestNoCredentialsRespondsAllChallenges(t *testing.T) {
+	reset()
+	registerAuthentication()
+	registerGetPrincipal()
+
+	request := httptest.NewRequest(http.MethodGet, "/principal", strings.NewReader(""))
+	recorder := httptest.NewRecorder()
+
+	HandleRequest(recorder, request)
+
+	challenges := recorder.Header()["Www-Authenticate"]
+
+	assert.Equal(t, recorder.Code, 401, "Response status code is 401")
+	assert.Equal(t, len(challenges), 3, "One challenge per authenticator is sent")
+	assert.Equal(t, challenges[1], "Bearer realm=\"cable\"", "Bearer challenge has no error")
+}
+
+func TestBearerAuthenticationInvalidToken(t *testing.T) {
+	reset()
+	registerAuthentication()
+	registerGetPrincipal()
+
+	request := httptest.NewRequest(http.MethodGet, "/principal", strings.NewReader(""))
+	request.Header.Set("Authorization", "bearer expired")
+	recorder := httptest.NewRecorder()
+
+	HandleRequest(recorder, request)
+
+	assert.Equal(t, recorder.Code, 401, "Response status code is 401")
+	assert.Equal(t, recorder.Header().Get("WWW-Authenticate"), "Bearer realm=\"cable\", error=\"invalid_token\"", "Bearer challenge has invalid_token error")
+}
+
+func TestBearerAuthenticationResolvesPrincipal(t *testing.T) {
+	reset()
+	registerAuthentication()
+	registerGetPrincipal()
+
+	request := httptest.NewRequest(http.MethodGet, "/principal", strings.NewReader(""))
+	request.Header.Set("Authorization", "Bearer valid")
+	recorder := httptest.NewRecorder()
+
+	HandleRequest(recorder, request)
+
+	assert.Equal(t, recorder.Code, 200, "Response status code is 200")
+	assert.Equal(t, recorder.Body.String(), "luigi Bearer", "Response body includes principal and scheme")
+}
+
+func TestAPIKeyAuthenticationFromQuery(t *testing.T) {
+	reset()
+	registerAuthentication()
+	registerGetPrincipal()
+
+	request := httptest.NewRequest(http.MethodGet, "/principal?api_key=k3y", strings.NewReader(""))
+	recorder := httptest.NewRecorder()
+
+	HandleRequest(recorder, request)
+
+	assert.Equal(t, recorder.Code, 200, "Response status code is 200")
+	assert.Equal(t, recorder.Body.String(), "toad APIKey", "Response body includes principal and scheme")
+}
+
+func TestBasicAuthenticationWithoutPrincipalResponds401(t *testing.T) {
+	reset()
+	Filter("/.*", NewAuthentication(NewBasicAuthenticator("cable", nilPrincipals{})).Filter)
+	registerGetPrincipal()
+
+	request := httptest.NewRequest(http.MethodGet, "/principal", strings.NewReader(""))
+	request.SetBasicAuth("mario", "secret")
+	recorder := httptest.NewRecorder()
+
+	HandleRequest(recorder, request)
+
+	assert.Equal(t, recorder.Code, 401, "Response status code is 401")
+}
+
+func TestBearerAuthenticationWithoutPrincipalResponds401(t *testing.T) {
+	reset()
+	Filter("/.*", NewAuthentication(NewBearerAuthenticator("cable", TokenVerifierFunc(func(token string) (*Principal, error) {
+		return nil, nil
+	}))).Filter)
+	registerGetPrincipal()
+
+	request := httptest.NewRequest(http.MethodGet, "/principal", strings.NewReader(""))
+	request.Header.Set("Authorization", "Bearer valid")
+	recorder := httptest.NewRecorder()
+
+	HandleRequest(recorder, request)
+
+	assert.Equal(t, recorder.Code, 401, "Response status code is 401")
+	assert.Equal(t, recorder.Header().Get("WWW-Authenticate"), "Bearer realm=\"cable\", error=\"invalid_token\"", "Bearer challenge has invalid_token error")
+}
+
+func TestAPIKeyAuthenticationWithoutPrincipalResponds401(t *testing.T) {
+	reset()
+	Filter("/.*", NewAuthentication(NewAPIKeyAuthenticator("cable", "X-Api-Key", "", nilPrincipals{})).Filter)
+	registerGetPrincipal()
+
+	request := httptest.NewRequest(http.MethodGet, "/principal", strings.NewReader(""))
+	request.Header.Set("X-Api-Key", "k3y")
+	recorder := httptest.NewRecorder()
+
+	HandleRequest(recorder, request)
+
+	assert.Equal(t, recorder.Code, 401, "Response status code is 401")
+}
+
+func TestPublicRouteAllowsAnonymous(t *testing.T) {
+	reset()
+	registerAuthentication()
+	registerGetPersons()
+
+	request := httptest.NewRequest(http.MethodGet, "/persons", strings.NewReader(""))
+	request.SetBasicAuth("mario", "wrong")
+	recorder := httptest.NewRecorder()
+
+	HandleRequest(recorder, request)
+
+	assert.Equal(t, recorder.Code, 200, "Response status code is 200")
+}
+
+func TestVerifyPassword(t *testing.T) {
+	hash, err := HashPassword("secret")
+
+	assert.Equal(t, err, nil, "Error should be nil")
+	assert.Equal(t, VerifyPassword(hash, "secret"), true, "Password matches its hash")
+	assert.Equal(t, VerifyPassword(hash, "Secret"), false, "Different password does not match")
+	assert.Equal(t, VerifyPassword("secret", "secret"), false, "Plain text is not a hash")
+}
+
+func registerAuthentication() {
+	hash, _ := HashPassword("secret")
+	credentials := NewMemoryCredentialStore()
+	credentials.Add("mario", hash, Principal{Roles: []string{"plumber"}})
+
+	keys := NewMemoryAPIKeyStore()
+	keys.Add("k3y", Principal{Name: "toad"})
+
+	tokens := TokenVerifierFunc(func(token string) (*Principal, error) {
+		if token == "valid" {
+			return &Principal{Name: "luigi"}, nil
+		}
+		return nil, errors.New("token expired")
+	})
+
+	authentication := NewAuthentication(
+		NewBasicAuthenticator("cable", credentials),
+		NewBearerAuthenticator("cable", tokens),
+		NewAPIKeyAuthenticator("cable", "X-Api-Key", "api_key", keys)).Public("/persons")
+
+	Filter("/.*", authentication.Filter)
+}
+
+func registerGetPrincipal() {
+	Get("/principal", func(req RequestEntity, resp *ResponseEntity) {
+		resp.Status = 200
+		resp.Body = []byte(req.Principal.Name + " " + req.Principal.Scheme)
+	})
+}
+
+// nilPrincipals is a credential and api key store resolving no principal without an error
+type nilPrincipals struct{}
+
+func (nilPrincipals) Verify(username string, password string) (*Principal, error) {
+	return nil, nil
+}
+
+func (nilPrincipals) Lookup(key string) (*Principal, error) {
+	return nil, nil
+}
@@ -0,0 +1,151 @@
+package cable
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+var (
+	authorizations = []mappedAuthorization{}
+)
+
+type mappedAuthorization struct {
+	Pattern      *regexp.Regexp
+	Requirements []Requirement
+	// pattern as registered, before it was compiled
+	pattern string
+}
+
+// Requirement is checked before a request is passed to its handler
+type Requirement interface {
+	Allows(principal *Principal, request *http.Request) bool
+	// String describes the requirement in the route introspection
+	String() string
+}
+
+type namesRequirement struct {
+	kind  string
+	names []string
+	of    func(principal *Principal) []string
+}
+
+func (n namesRequirement) Allows(principal *Principal, request *http.Request) bool {
+	if principal == nil {
+		return false
+	}
+
+loop:
+	for _, name := range n.names {
+		for _, granted := range n.of(principal) {
+			if name == granted {
+				continue loop
+			}
+		}
+
+		return false
+	}
+
+	return true
+}
+
+func (n namesRequirement) String() string {
+	return fmt.Sprintf("%s(%s)", n.kind, strings.Join(n.names, ","))
+}
+
+// RequireRoles requires the principal to have all of the given roles
+func RequireRoles(roles ...string) Requirement {
+	return namesRequirement{kind: "roles", names: roles, of: func(p *Principal) []string { return p.Roles }}
+}
+
+// RequireScopes requires the principal to be granted all of the given scopes
+func RequireScopes(scopes ...string) Requirement {
+	return namesRequirement{kind: "scopes", names: scopes, of: func(p *Principal) []string { return p.Scopes }}
+}
+
+// RequirePermissions requires the principal to have all of the given permissions
+func RequirePermissions(permissions ...string) Requirement {
+	return namesRequirement{kind: "permissions", names: permissions, of: func(p *Principal) []string { return p.Permissions }}
+}
+
+type policyRequirement struct {
+	name   string
+	policy func(principal *Principal, request *http.Request) bool
+}
+
+func (p policyRequirement) Allows(principal *Principal, request *http.Request) bool {
+	return p.policy(principal, request)
+}
+
+func (p policyRequirement) String() string {
+	return fmt.Sprintf("policy(%s)", p.name)
+}
+
+// Policy requires a custom decision, e.g. that the principal owns the requested resource.
+// The principal is nil for anonymous requests.
+func Policy(name string, policy func(principal *Principal, request *http.Request) bool) Requirement {
+	return policyRequirement{name: name, policy: policy}
+}
+
+func registerAuthorization(pattern string, requirements []Requirement) {
+	compiledPattern := stringToRegex(pattern)
+	authorizations = append(authorizations, mappedAuthorization{Pattern: compiledPattern, Requirements: requirements, pattern: pattern})
+
+	logger.Info("Registered Authorization",
+		zap.String("Pattern", pattern),
+		zap.Stringer("Requirements", requirementList(requirements)))
+}
+
+// findAuthorizations returns the authorizations of a route, identified by the pattern it
+// was registered with. An authorization applies to a route if it was registered with the
+// same pattern or its pattern matches the route pattern, e.g. a group like /admin/.*.
+// Routes are authorized by their pattern rather than by the requested path, so that the
+// requirements checked are exactly the requirements listed by Routes.
+func findAuthorizations(routePattern string) []mappedAuthorization {
+	matchingAuthorizations := []mappedAuthorization{}
+
+	for _, r := range authorizations {
+		if r.pattern == routePattern || len(r.Pattern.FindString(routePattern)) > 0 {
+			matchingAuthorizations = append(matchingAuthorizations, r)
+		}
+	}
+
+	return matchingAuthorizations
+}
+
+func authorize(request *http.Request, principal *Principal, routePattern string) bool {
+	for _, authorization := range findAuthorizations(routePattern) {
+		for _, requirement := range authorization.Requirements {
+			if !requirement.Allows(principal, request) {
+				name := ""
+				if principal != nil {
+					name = principal.Name
+				}
+
+				logger.Info("Authorization denied",
+					zap.String("Path", request.URL.Path),
+					zap.String("Principal", name),
+					zap.Stringer("Requirement", requirement))
+
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+type requirementList []Requirement
+
+func (r requirementList) String() string {
+	descriptions := []string{}
+
+	for _, requirement := range r {
+		descriptions = append(descriptions, requirement.String())
+	}
+
+	return strings.Join(descriptions, ",")
+}
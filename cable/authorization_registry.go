@@ -0,0 +1,8 @@
+package cable
+
+// Authorize requires the principal of every request to a route to fulfill all requirements.
+// pattern is either the pattern the route was registered with, e.g. /persons/[0-9]+, or a
+// pattern matching the patterns of a group of routes, e.g. /admin/.*
+func Authorize(pattern string, requirements ...Requirement) {
+	registerAuthorization(pattern, requirements)
+}
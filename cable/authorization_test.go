@@ -0,0 +1,129 @@
+package cable
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	assert "github.com/stfsy/golang-assert"
+)
+
+func TestAuthorizedRoleResponds200(t *testing.T) {
+	reset()
+	registerAuthenticatedAs(&Principal{Name: "mario", Roles: []string{"admin", "plumber"}})
+	registerGetAdminUsers()
+	Authorize("/admin/.*", RequireRoles("admin"))
+
+	recorder := getAdminUsers()
+
+	assert.Equal(t, recorder.Code, 200, "Response status code is 200")
+}
+
+func TestMissingRoleResponds403(t *testing.T) {
+	reset()
+	registerAuthenticatedAs(&Principal{Name: "luigi", Roles: []string{"plumber"}})
+	registerGetAdminUsers()
+	Authorize("/admin/.*", RequireRoles("admin"))
+
+	recorder := getAdminUsers()
+
+	assert.Equal(t, recorder.Code, 403, "Response status code is 403")
+	assert.Equal(t, strings.Contains(recorder.Body.String(), "Users"), false, "Handler is not called")
+}
+
+func TestAnonymousResponds403(t *testing.T) {
+	reset()
+	registerGetAdminUsers()
+	Authorize("/admin/users", RequireScopes("users:read"))
+
+	recorder := getAdminUsers()
+
+	assert.Equal(t, recorder.Code, 403, "Response status code is 403")
+}
+
+func TestGroupAndRouteRequirementsAreCombined(t *testing.T) {
+	reset()
+	registerAuthenticatedAs(&Principal{Name: "mario", Roles: []string{"admin"}, Scopes: []string{"users:read"}})
+	registerGetAdminUsers()
+	Authorize("/admin/.*", RequireRoles("admin"))
+	Authorize("/admin/users", RequirePermissions("users:delete"))
+
+	recorder := getAdminUsers()
+
+	assert.Equal(t, recorder.Code, 403, "Response status code is 403")
+}
+
+func TestPolicyDecidesOnPrincipalAndRequest(t *testing.T) {
+	reset()
+	registerAuthenticatedAs(&Principal{Name: "mario"})
+	registerGetAdminUsers()
+	Authorize("/admin/.*", Policy("own-tenant", func(principal *Principal, request *http.Request) bool {
+		return principal.Name == request.URL.Query().Get("tenant")
+	}))
+
+	request := httptest.NewRequest(http.MethodGet, "/admin/users?tenant=mario", strings.NewReader(""))
+	recorder := httptest.NewRecorder()
+
+	HandleRequest(recorder, request)
+
+	assert.Equal(t, recorder.Code, 200, "Response status code is 200")
+}
+
+func TestRoutesListAuthorization(t *testing.T) {
+	reset()
+	registerGetAdminUsers()
+	registerPostPersons()
+	Authorize("/admin/.*", RequireRoles("admin"))
+	Authorize("/admin/users", Policy("own-tenant", nil))
+
+	routes := Routes()
+
+	assert.Equal(t, len(routes), 2, "Two routes are registered")
+	assert.Equal(t, routes[0], Route{Method: http.MethodGet, Pattern: "/admin/users", Authorization: []string{"roles(admin)", "policy(own-tenant)"}}, "GET route lists its requirements")
+	assert.Equal(t, routes[1], Route{Method: http.MethodPost, Pattern: "/persons", Authorization: []string{}}, "POST route has no requirements")
+}
+
+func TestRoutesListAuthorizationOfRegexRoute(t *testing.T) {
+	reset()
+	Get("/persons/[0-9]+", func(req RequestEntity, resp *ResponseEntity) {
+		resp.Status = 200
+	})
+	Authorize("/persons/.*", RequireRoles("user"))
+	Authorize("/persons/[0-9]+", RequireRoles("admin"))
+	Authorize("/persons/[a-z]+", RequireRoles("guest"))
+
+	routes := Routes()
+
+	assert.Equal(t, routes[0].Authorization, []string{"roles(user)", "roles(admin)"}, "Route lists group and route requirements")
+
+	registerAuthenticatedAs(&Principal{Name: "mario", Roles: []string{"user"}})
+	request := httptest.NewRequest(http.MethodGet, "/persons/12", strings.NewReader(""))
+	recorder := httptest.NewRecorder()
+
+	HandleRequest(recorder, request)
+
+	assert.Equal(t, recorder.Code, 403, "Listed admin requirement is checked")
+}
+
+func registerAuthenticatedAs(principal *Principal) {
+	Filter("/.*", func(writer http.ResponseWriter, request *http.Request) {
+		scopeOf(request).principal = principal
+	})
+}
+
+func registerGetAdminUsers() {
+	Get("/admin/users", func(req RequestEntity, resp *ResponseEntity) {
+		resp.Status = 200
+		resp.Body = []byte("Users")
+	})
+}
+
+func getAdminUsers() *httptest.ResponseRecorder {
+	request := httptest.NewRequest(http.MethodGet, "/admin/users", strings.NewReader(""))
+	recorder := httptest.NewRecorder()
+
+	HandleRequest(recorder, request)
+
+	return recorder
+}
@@ -0,0 +1,115 @@
+package cable
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrBodyTooLarge is returned when reading a request body exceeding its BodyLimit
+	ErrBodyTooLarge = errors.New("request body too large")
+
+	defaultBodyLimit = BodyLimit{JSON: 1 << 20, Multipart: 32 << 20, Default: 1 << 20}
+	bodyLimit        = defaultBodyLimit
+)
+
+// BodyLimit is the maximum size in bytes of request bodies by content type
+type BodyLimit struct {
+	JSON      int64
+	Multipart int64
+	// Default applies to all other content types
+	Default int64
+}
+
+// SetBodyLimit changes the limits of all routes. Zero values keep the current limit.
+func SetBodyLimit(limit BodyLimit) {
+	bodyLimit = bodyLimit.merge(limit)
+}
+
+// LimitBody returns a filter changing the limits for a group of routes or a single route.
+// Zero values keep the limit of the group or the global limit. Register it with
+// cable.Filter(pattern, cable.LimitBody(limit)) before filters reading the body, e.g. CSRF.
+func LimitBody(limit BodyLimit) func(writer http.ResponseWriter, request *http.Request) {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		scope := scopeOf(request)
+		merged := scope.effectiveBodyLimit().merge(limit)
+		scope.bodyLimit = &merged
+	}
+}
+
+func (b BodyLimit) merge(other BodyLimit) BodyLimit {
+	if other.JSON > 0 {
+		b.JSON = other.JSON
+	}
+
+	if other.Multipart > 0 {
+		b.Multipart = other.Multipart
+	}
+
+	if other.Default > 0 {
+		b.Default = other.Default
+	}
+
+	return b
+}
+
+func (b BodyLimit) forContentType(contentType string) int64 {
+	mediatype, _, _ := mime.ParseMediaType(contentType)
+
+	if mediatype == "multipart/form-data" {
+		return b.Multipart
+	}
+
+	if mediatype == "application/json" || strings.HasSuffix(mediatype, "+json") {
+		return b.JSON
+	}
+
+	return b.Default
+}
+
+func (s *requestScope) effectiveBodyLimit() BodyLimit {
+	if s.bodyLimit != nil {
+		return *s.bodyLimit
+	}
+
+	return bodyLimit
+}
+
+// limitBody responds 413 if the declared content length exceeds the limit and
+// otherwise makes reading the body fail once the limit is exceeded
+func limitBody(writer http.ResponseWriter, request *http.Request) bool {
+	limit := scopeOf(request).effectiveBodyLimit().forContentType(request.Header.Get(contentTypeHeader))
+
+	if request.ContentLength > limit {
+		logger.Info("Request body too large",
+			zap.String("Path", request.URL.Path),
+			zap.Int64("ContentLength", request.ContentLength),
+			zap.Int64("Limit", limit))
+
+		writeError(writer, request, http.StatusRequestEntityTooLarge, ErrBodyTooLarge.Error())
+		return false
+	}
+
+	request.Body = http.MaxBytesReader(writer, request.Body, limit)
+
+	return true
+}
+
+// prepareBody decodes and limits the request body once, either for the first filter
+// reading it or before the request handler. Limits set by filters running afterwards
+// do not apply to the request anymore.
+func prepareBody(writer http.ResponseWriter, request *http.Request) bool {
+	scope := scopeOf(request)
+
+	if scope.bodyPrepared {
+		return true
+	}
+
+	scope.bodyPrepared = true
+
+	return decodeBody(writer, request) && limitBody(writer, request)
+}
@@ -0,0 +1,103 @@
+package cable
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	assert "github.com/stfsy/golang-assert"
+)
+
+func TestBodyLimitContentLengthResponds413(t *testing.T) {
+	reset()
+	SetBodyLimit(BodyLimit{JSON: 10})
+	registerPostPersons()
+
+	request := httptest.NewRequest(http.MethodPost, "/persons", strings.NewReader(jsonBody))
+	request.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+
+	HandleRequest(recorder, request)
+
+	assert.Equal(t, recorder.Code, 413, "Response status code is 413")
+	assert.Equal(t, recorder.Header().Get("Content-Type"), "application/problem+json", "Error body is a problem")
+	assert.Equal(t, recorder.Body.String(), `{"type":"about:blank","title":"Request Entity Too Large","status":413,"detail":"request body too large","instance":"/persons"}`, "Error body describes the error")
+}
+
+func TestBodyLimitErrorBodyIsNegotiated(t *testing.T) {
+	reset()
+	SetBodyLimit(BodyLimit{JSON: 10})
+	registerPostPersons()
+
+	request := httptest.NewRequest(http.MethodPost, "/persons", strings.NewReader(jsonBody))
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Accept", "application/xml")
+	recorder := httptest.NewRecorder()
+
+	HandleRequest(recorder, request)
+
+	assert.Equal(t, recorder.Code, 413, "Response status code is 413")
+	assert.Equal(t, recorder.Body.String(), `<problem xmlns="urn:ietf:rfc:7807"><type>about:blank</type><title>Request Entity Too Large</title><status>413</status><detail>request body too large</detail><instance>/persons</instance></problem>`, "Error body is XML")
+}
+
+func TestBodyLimitUnknownLengthFailsUnmarshal(t *testing.T) {
+	reset()
+	SetBodyLimit(BodyLimit{JSON: 10})
+	registerPostPersonUnmarshal()
+
+	request := httptest.NewRequest(http.MethodPost, "/persons", io.MultiReader(strings.NewReader(jsonBody)))
+	request.Header.Set("Content-Type", "application/json")
+	request.ContentLength = -1
+	recorder := httptest.NewRecorder()
+
+	HandleRequest(recorder, request)
+
+	assert.Equal(t, recorder.Code, 413, "Response status code is 413")
+}
+
+func TestBodyLimitRouteOverridesGroup(t *testing.T) {
+	reset()
+	Filter("/persons/.*", LimitBody(BodyLimit{JSON: 10}))
+	Filter("/persons/import", LimitBody(BodyLimit{JSON: 1 << 10}))
+	Post("/persons/import", func(req RequestEntity, resp *ResponseEntity) {
+		resp.Status = 200
+	})
+
+	request := httptest.NewRequest(http.MethodPost, "/persons/import", strings.NewReader(jsonBody))
+	request.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+
+	HandleRequest(recorder, request)
+
+	assert.Equal(t, recorder.Code, 200, "Response status code is 200")
+}
+
+func TestBodyLimitMultipartIsDistinct(t *testing.T) {
+	reset()
+	Filter("/persons", LimitBody(BodyLimit{JSON: 10, Multipart: 1 << 10}))
+	registerPostPersons()
+
+	request := httptest.NewRequest(http.MethodPost, "/persons", strings.NewReader(jsonBody))
+	request.Header.Set("Content-Type", "multipart/form-data; boundary=x")
+	recorder := httptest.NewRecorder()
+
+	HandleRequest(recorder, request)
+
+	assert.Equal(t, recorder.Code, 200, "Response status code is 200")
+}
+
+func registerPostPersonUnmarshal() {
+	Post("/persons", func(req RequestEntity, resp *ResponseEntity) {
+		err := UnmarshalBody(&Person{}, req.Request)
+
+		if errors.Is(err, ErrBodyTooLarge) {
+			resp.Status = http.StatusRequestEntityTooLarge
+			return
+		}
+
+		resp.Status = 200
+	})
+}
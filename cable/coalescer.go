@@ -0,0 +1,142 @@
+package cable
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// Coalescer lets concurrent identical GET requests share one execution of the request handler.
+type Coalescer struct {
+	mutex sync.Mutex
+	calls map[string]*coalescedCall
+}
+
+type coalescedCall struct {
+	done     chan struct{}
+	response ResponseEntity
+	panicked bool
+	// private is set if the response depends on the session of the first request
+	private bool
+	// header of the first request, selecting the response if it varies by request headers
+	header http.Header
+	// duplicates is the number of requests waiting for the response
+	duplicates int
+}
+
+// NewCoalescer creates a coalescer for the routes it is registered for with
+// cable.Intercept(pattern, coalescer.Intercept). Register it after cable.ResponseCache
+// to protect handlers from requests arriving while a cached response expires.
+func NewCoalescer() *Coalescer {
+	return &Coalescer{calls: map[string]*coalescedCall{}}
+}
+
+func (c *Coalescer) Intercept(requestEntity RequestEntity, response *ResponseEntity, next func(requestEntity RequestEntity, response *ResponseEntity)) {
+	request := requestEntity.Request
+
+	if (request.Method != http.MethodGet && request.Method != http.MethodHead) || sessionDependent(request) {
+		next(requestEntity, response)
+		return
+	}
+
+	key := coalescingKey(requestEntity)
+
+	c.mutex.Lock()
+
+	if call, ok := c.calls[key]; ok {
+		call.duplicates++
+		c.mutex.Unlock()
+		c.wait(call, requestEntity, response, next)
+		return
+	}
+
+	call := &coalescedCall{done: make(chan struct{}), panicked: true, header: request.Header}
+	c.calls[key] = call
+	c.mutex.Unlock()
+
+	defer func() {
+		c.mutex.Lock()
+		delete(c.calls, key)
+		c.mutex.Unlock()
+
+		if call.duplicates > 0 {
+			logger.Debug("Coalesced requests",
+				zap.String("Path", request.URL.Path),
+				zap.Int("Duplicates", call.duplicates))
+		}
+
+		close(call.done)
+	}()
+
+	next(requestEntity, response)
+
+	call.response = copyResponseEntity(*response)
+	call.private = sessionDependent(request)
+	call.panicked = false
+}
+
+func (c *Coalescer) wait(call *coalescedCall, requestEntity RequestEntity, response *ResponseEntity, next func(requestEntity RequestEntity, response *ResponseEntity)) {
+	request := requestEntity.Request
+
+	select {
+	case <-call.done:
+	case <-request.Context().Done():
+		setError(response, request, http.StatusServiceUnavailable, http.StatusText(http.StatusServiceUnavailable))
+		return
+	}
+
+	if call.panicked {
+		setError(response, request, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+		return
+	}
+
+	if call.private || !sameVariant(call.response.Header[varyHeader], call.header, request.Header) {
+		next(requestEntity, response)
+		return
+	}
+
+	shared := copyResponseEntity(call.response)
+	shared.Request = request
+	// cookies are specific to the client of the first request
+	shared.Cookies = nil
+	*response = shared
+}
+
+// coalescingKey identifies requests with the same route, parameters, negotiated type and
+// encoding and credentials. Responses are never shared between principals or clients
+// sending cookies. Other request headers named in the Vary header of a response are
+// compared by sameVariant once the response is known.
+func coalescingKey(requestEntity RequestEntity) string {
+	request := requestEntity.Request
+	key := request.Method + " " + request.URL.Path + "?" + request.URL.Query().Encode() +
+		"\n" + request.Header.Get(acceptHeader) +
+		"\n" + request.Header.Get(acceptEncodingHeader) +
+		"\n" + strings.Join(request.Header.Values(cookieHeader), "; ") +
+		"\n" + request.Header.Get(authorizationHeader)
+
+	if requestEntity.Principal != nil {
+		key += "\n" + requestEntity.Principal.Scheme + "\n" + requestEntity.Principal.Name
+	}
+
+	return key
+}
+
+// sameVariant returns true if the request headers listed in vary are equal, so both
+// requests select the same representation. Vary: * matches no other request.
+func sameVariant(vary string, first http.Header, second http.Header) bool {
+	for _, header := range strings.Split(vary, ",") {
+		header = strings.TrimSpace(header)
+
+		if header == "*" {
+			return false
+		}
+
+		if len(header) > 0 && strings.Join(first.Values(header), ",") != strings.Join(second.Values(header), ",") {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,201 @@
+package cable
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	assert "github.com/stfsy/golang-assert"
+)
+
+func TestCoalescerSharesHandlerExecution(t *testing.T) {
+	reset()
+	calls := int32(0)
+	started := make(chan struct{})
+	release := make(chan struct{})
+	coalescer := NewCoalescer()
+	Intercept("/persons", coalescer.Intercept)
+	Get("/persons", func(req RequestEntity, resp *ResponseEntity) {
+		atomic.AddInt32(&calls, 1)
+		close(started)
+		<-release
+		resp.Status = 200
+		resp.SetHeader("Content-Type", "text/plain")
+		resp.Body = []byte("Some Persons")
+	})
+
+	leader := make(chan *httptest.ResponseRecorder)
+	go func() {
+		leader <- getCoalesced("application/json")
+	}()
+	<-started
+
+	followers := make([]*httptest.ResponseRecorder, 10)
+	waitGroup := sync.WaitGroup{}
+
+	for i := range followers {
+		waitGroup.Add(1)
+		go func(i int) {
+			defer waitGroup.Done()
+			followers[i] = getCoalesced("application/json")
+		}(i)
+	}
+
+	// wait until all followers are waiting for the leader
+	for coalescer.waiting() < len(followers) {
+		runtime.Gosched()
+	}
+
+	close(release)
+	waitGroup.Wait()
+
+	assert.Equal(t, (<-leader).Body.String(), "Some Persons", "Leader gets the response")
+
+	for _, follower := range followers {
+		assert.Equal(t, follower.Code, 200, "Follower response status code is 200")
+		assert.Equal(t, follower.Body.String(), "Some Persons", "Follower gets a copy of the response")
+		assert.Equal(t, follower.Header().Get("Content-Type"), "text/plain", "Follower gets the headers")
+	}
+
+	assert.Equal(t, atomic.LoadInt32(&calls), int32(1), "Handler is called once")
+}
+
+func TestCoalescerKeyIncludesNegotiatedType(t *testing.T) {
+	request := httptest.NewRequest(http.MethodGet, "/persons?b=2&a=1", strings.NewReader(""))
+	request.Header.Set("Accept", "application/xml")
+	reordered := httptest.NewRequest(http.MethodGet, "/persons?a=1&b=2", strings.NewReader(""))
+	reordered.Header.Set("Accept", "application/xml")
+	json := httptest.NewRequest(http.MethodGet, "/persons?a=1&b=2", strings.NewReader(""))
+	json.Header.Set("Accept", "application/json")
+
+	assert.Equal(t, coalescingKey(RequestEntity{Request: request}), coalescingKey(RequestEntity{Request: reordered}), "Parameter order is ignored")
+	assert.NotEqual(t, coalescingKey(RequestEntity{Request: request}), coalescingKey(RequestEntity{Request: json}), "Accepted types differ")
+
+	gzip := httptest.NewRequest(http.MethodGet, "/persons?a=1&b=2", strings.NewReader(""))
+	gzip.Header.Set("Accept", "application/xml")
+	gzip.Header.Set("Accept-Encoding", "gzip")
+
+	assert.NotEqual(t, coalescingKey(RequestEntity{Request: request}), coalescingKey(RequestEntity{Request: gzip}), "Accepted encodings differ")
+	assert.NotEqual(t, coalescingKey(RequestEntity{Request: request}), coalescingKey(RequestEntity{Request: request, Principal: &Principal{Name: "mario"}}), "Principals differ")
+
+	cookie := httptest.NewRequest(http.MethodGet, "/persons?a=1&b=2", strings.NewReader(""))
+	cookie.Header.Set("Accept", "application/xml")
+	cookie.Header.Set("Cookie", "session=mario")
+	authorization := httptest.NewRequest(http.MethodGet, "/persons?a=1&b=2", strings.NewReader(""))
+	authorization.Header.Set("Accept", "application/xml")
+	authorization.Header.Set("Authorization", "Bearer mario")
+
+	assert.NotEqual(t, coalescingKey(RequestEntity{Request: request}), coalescingKey(RequestEntity{Request: cookie}), "Cookies differ")
+	assert.NotEqual(t, coalescingKey(RequestEntity{Request: request}), coalescingKey(RequestEntity{Request: authorization}), "Credentials differ")
+}
+
+func TestCoalescerDoesNotShareOtherVariants(t *testing.T) {
+	reset()
+	calls := int32(0)
+	started := make(chan struct{}, 2)
+	release := make(chan struct{})
+	coalescer := NewCoalescer()
+	Intercept("/persons", coalescer.Intercept)
+	Get("/persons", func(req RequestEntity, resp *ResponseEntity) {
+		atomic.AddInt32(&calls, 1)
+		started <- struct{}{}
+		<-release
+		resp.Status = 200
+		resp.SetHeader("Vary", "Accept-Language")
+		resp.Body = []byte(req.Request.Header.Get("Accept-Language"))
+	})
+
+	german := make(chan *httptest.ResponseRecorder, 1)
+	english := make(chan *httptest.ResponseRecorder, 1)
+	go func() {
+		german <- getCoalescedIn("de")
+	}()
+	<-started
+	go func() {
+		english <- getCoalescedIn("en")
+	}()
+
+	for coalescer.waiting() < 1 {
+		runtime.Gosched()
+	}
+
+	close(release)
+
+	assert.Equal(t, (<-german).Body.String(), "de", "Leader gets its variant")
+	assert.Equal(t, (<-english).Body.String(), "en", "Waiter gets its variant")
+	assert.Equal(t, atomic.LoadInt32(&calls), int32(2), "Handler is called per variant")
+}
+
+func TestCoalescerDoesNotShareSessions(t *testing.T) {
+	reset()
+	calls := int32(0)
+	release := make(chan struct{})
+	coalescer := NewCoalescer()
+	registerSessionLogin(NewCookieSessions(sessionKey))
+	Intercept("/persons", coalescer.Intercept)
+	Get("/persons", func(req RequestEntity, resp *ResponseEntity) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		user, _ := req.Session.Get("user").(string)
+		resp.Status = 200
+		resp.Body = []byte(user)
+	})
+
+	alice, bob := login("alice"), login("bob")
+	responses := map[string]chan *httptest.ResponseRecorder{}
+
+	for name, cookie := range map[string]*http.Cookie{"alice": alice, "bob": bob} {
+		responses[name] = make(chan *httptest.ResponseRecorder, 1)
+		go func(cookie *http.Cookie, response chan *httptest.ResponseRecorder) {
+			response <- getWithSession(cookie)
+		}(cookie, responses[name])
+	}
+
+	// wait until both requests are handled or one waits for the other
+	for atomic.LoadInt32(&calls) < 2 && coalescer.waiting() == 0 {
+		runtime.Gosched()
+	}
+
+	close(release)
+
+	assert.Equal(t, (<-responses["alice"]).Body.String(), "alice", "Alice gets her response")
+	assert.Equal(t, (<-responses["bob"]).Body.String(), "bob", "Bob gets his response")
+	assert.Equal(t, atomic.LoadInt32(&calls), int32(2), "Handler is called per session")
+}
+
+func (c *Coalescer) waiting() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	waiting := 0
+
+	for _, call := range c.calls {
+		waiting += call.duplicates
+	}
+
+	return waiting
+}
+
+func getCoalescedIn(language string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(http.MethodGet, "/persons", strings.NewReader(""))
+	request.Header.Set("Accept-Language", language)
+	recorder := httptest.NewRecorder()
+
+	HandleRequest(recorder, request)
+
+	return recorder
+}
+
+func getCoalesced(accept string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(http.MethodGet, "/persons", strings.NewReader(""))
+	request.Header.Set("Accept", accept)
+	recorder := httptest.NewRecorder()
+
+	HandleRequest(recorder, request)
+
+	return recorder
+}
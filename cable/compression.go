@@ -0,0 +1,323 @@
+package cable
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+const (
+	acceptEncodingHeader  = "Accept-Encoding"
+	contentEncodingHeader = "Content-Encoding"
+	varyHeader            = "Vary"
+)
+
+// Encoder compresses response bodies with a content coding, e.g. gzip. Brotli or zstd
+// encoders backed by third party libraries can be added with Compression.Use.
+type Encoder interface {
+	// Encoding is the token used in Accept-Encoding and Content-Encoding headers
+	Encoding() string
+	Encode(body []byte) ([]byte, error)
+}
+
+type gzipEncoder struct {
+	level int
+}
+
+// GzipEncoder compresses with level, e.g. gzip.DefaultCompression
+func GzipEncoder(level int) Encoder {
+	return gzipEncoder{level: level}
+}
+
+func (g gzipEncoder) Encoding() string {
+	return "gzip"
+}
+
+func (g gzipEncoder) Encode(body []byte) ([]byte, error) {
+	buffer := bytes.Buffer{}
+	writer, err := gzip.NewWriterLevel(&buffer, g.level)
+
+	if err != nil {
+		return nil, err
+	}
+
+	writer.Write(body)
+	err = writer.Close()
+
+	return buffer.Bytes(), err
+}
+
+type deflateEncoder struct {
+	level int
+}
+
+/*
+* The "deflate" coding is a "zlib" data format containing a "deflate" compressed
+* data stream.
+*
+* https://tools.ietf.org/html/rfc7230#section-4.2.2
+ */
+func DeflateEncoder(level int) Encoder {
+	return deflateEncoder{level: level}
+}
+
+func (d deflateEncoder) Encoding() string {
+	return "deflate"
+}
+
+func (d deflateEncoder) Encode(body []byte) ([]byte, error) {
+	buffer := bytes.Buffer{}
+	writer, err := zlib.NewWriterLevel(&buffer, d.level)
+
+	if err != nil {
+		return nil, err
+	}
+
+	writer.Write(body)
+	err = writer.Close()
+
+	return buffer.Bytes(), err
+}
+
+// Compression encodes response bodies with the coding preferred by the client.
+type Compression struct {
+	// MinSize is the smallest body in bytes worth compressing
+	MinSize int
+	// ContentTypes are compressed, e.g. text/*, application/json or application/*+json.
+	// Bodies without Content-Type header are sniffed.
+	ContentTypes []string
+	// Encoders in order of preference if the client accepts several with the same quality
+	Encoders []Encoder
+}
+
+// NewCompression compresses textual bodies of at least 1 KiB with gzip or deflate.
+// Register it with cable.Intercept(pattern, compression.Intercept).
+func NewCompression() *Compression {
+	return &Compression{
+		MinSize: 1024,
+		ContentTypes: []string{
+			"text/*",
+			"application/json",
+			"application/*+json",
+			"application/xml",
+			"application/*+xml",
+			"application/javascript",
+			"image/svg+xml"},
+		Encoders: []Encoder{
+			GzipEncoder(gzip.DefaultCompression),
+			DeflateEncoder(flate.DefaultCompression)}}
+}
+
+// Use prefers encoders over the ones already configured
+func (c *Compression) Use(encoders ...Encoder) *Compression {
+	c.Encoders = append(append([]Encoder{}, encoders...), c.Encoders...)
+
+	return c
+}
+
+func (c *Compression) Intercept(requestEntity RequestEntity, response *ResponseEntity, next func(requestEntity RequestEntity, response *ResponseEntity)) {
+	next(requestEntity, response)
+
+	if response.Status == http.StatusNoContent || response.Status == http.StatusNotModified || len(response.Body) == 0 {
+		return
+	}
+
+	if response.Header == nil {
+		response.Header = map[string]string{}
+	}
+
+	// already encoded, e.g. by a handler serving pre-compressed files
+	if len(response.Header[contentEncodingHeader]) > 0 {
+		return
+	}
+
+	contentType := response.Header[contentTypeHeader]
+
+	if len(contentType) == 0 {
+		contentType = http.DetectContentType(response.Body)
+	}
+
+	if !c.compresses(contentType) {
+		return
+	}
+
+	// the response depends on Accept-Encoding even if it ends up not being encoded
+	addVary(response, acceptEncodingHeader)
+
+	if len(response.Body) < c.MinSize {
+		return
+	}
+
+	encoder := c.negotiate(requestEntity.Request.Header.Values(acceptEncodingHeader))
+
+	if encoder == nil {
+		return
+	}
+
+	body, err := encoder.Encode(response.Body)
+
+	if err != nil {
+		logger.Error("Compressing response failed",
+			zap.String("Path", requestEntity.Request.URL.Path),
+			zap.String("Encoding", encoder.Encoding()),
+			zap.Error(err))
+		return
+	}
+
+	response.Body = body
+	response.Header[contentEncodingHeader] = encoder.Encoding()
+
+	// a strong validator must differ between representations
+	if etag := response.Header[etagHeader]; strings.HasPrefix(etag, `"`) {
+		response.Header[etagHeader] = encodedETag(etag, encoder.Encoding())
+	}
+}
+
+// contentCodings holds the encodings appended to entity-tags of compressed responses
+var contentCodings = sync.Map{}
+
+func init() {
+	contentCodings.Store("gzip", true)
+	contentCodings.Store("deflate", true)
+}
+
+// encodedETag appends encoding to the opaque tag, e.g. "v42" becomes "v42-gzip"
+func encodedETag(etag string, encoding string) string {
+	contentCodings.Store(encoding, true)
+
+	return strings.TrimSuffix(etag, `"`) + "-" + encoding + `"`
+}
+
+// decodedETag reverts encodedETag, returning the entity-tag set by the request handler
+// for the unencoded representation and false if etag has no content coding suffix
+func decodedETag(etag string) (string, bool) {
+	suffix := strings.LastIndex(etag, "-")
+
+	if suffix < 0 || !strings.HasSuffix(etag, `"`) {
+		return "", false
+	}
+
+	if _, ok := contentCodings.Load(etag[suffix+1 : len(etag)-1]); !ok {
+		return "", false
+	}
+
+	return etag[:suffix] + `"`, true
+}
+
+func (c *Compression) compresses(contentType string) bool {
+	mediatype, _, err := mime.ParseMediaType(contentType)
+
+	if err != nil {
+		return false
+	}
+
+	for _, allowed := range c.ContentTypes {
+		if mediaTypeMatches(allowed, mediatype) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// mediaTypeMatches supports wildcard subtypes like text/* and structured syntax suffixes
+// like application/*+json
+func mediaTypeMatches(pattern string, mediatype string) bool {
+	if pattern == mediatype {
+		return true
+	}
+
+	if strings.HasSuffix(pattern, "/*") {
+		return strings.HasPrefix(mediatype, strings.TrimSuffix(pattern, "*"))
+	}
+
+	if i := strings.Index(pattern, "/*+"); i > 0 {
+		return strings.HasPrefix(mediatype, pattern[:i+1]) && strings.HasSuffix(mediatype, pattern[i+2:])
+	}
+
+	return false
+}
+
+/*
+* Accept-Encoding: gzip;q=1.0, identity; q=0.5, *;q=0
+*
+* A coding with a qvalue of 0 is "not acceptable". The "*" matches any coding not
+* explicitly listed in the header field.
+*
+* https://tools.ietf.org/html/rfc7231#section-5.3.4
+ */
+func (c *Compression) negotiate(values []string) Encoder {
+	qualities := parseQualities(values)
+	var preferred Encoder
+	preferredQuality := 0.0
+
+	for _, encoder := range c.Encoders {
+		q, listed := qualities[encoder.Encoding()]
+
+		if !listed {
+			q = qualities["*"]
+		}
+
+		if q > preferredQuality {
+			preferred, preferredQuality = encoder, q
+		}
+	}
+
+	return preferred
+}
+
+// parseQualities maps the lower cased tokens of a header like Accept-Encoding to their qvalue
+func parseQualities(values []string) map[string]float64 {
+	qualities := map[string]float64{}
+
+	for _, value := range values {
+		for _, element := range strings.Split(value, ",") {
+			params := strings.Split(element, ";")
+			token := strings.ToLower(strings.TrimSpace(params[0]))
+
+			if len(token) == 0 {
+				continue
+			}
+
+			q := 1.0
+
+			for _, param := range params[1:] {
+				kv := strings.SplitN(strings.TrimSpace(param), "=", 2)
+
+				if len(kv) == 2 && strings.EqualFold(kv[0], "q") {
+					if parsed, err := strconv.ParseFloat(kv[1], 64); err == nil {
+						q = parsed
+					}
+				}
+			}
+
+			qualities[token] = q
+		}
+	}
+
+	return qualities
+}
+
+func addVary(response *ResponseEntity, header string) {
+	vary := response.Header[varyHeader]
+
+	for _, value := range strings.Split(vary, ",") {
+		if strings.EqualFold(strings.TrimSpace(value), header) {
+			return
+		}
+	}
+
+	if len(vary) > 0 {
+		vary += ", "
+	}
+
+	response.Header[varyHeader] = vary + header
+}
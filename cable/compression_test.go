@@ -0,0 +1,148 @@
+package cable
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	assert "github.com/stfsy/golang-assert"
+)
+
+var largeBody = strings.Repeat(`{"firstName":"Mario","lastName":"Mario"}`, 100)
+
+func TestCompressionGzip(t *testing.T) {
+	reset()
+	registerCompressedBody("application/json", largeBody)
+
+	recorder := getCompressed("gzip, deflate")
+	reader, _ := gzip.NewReader(recorder.Body)
+	body, _ := ioutil.ReadAll(reader)
+
+	assert.Equal(t, recorder.Header().Get("Content-Encoding"), "gzip", "Body is gzip encoded")
+	assert.Equal(t, recorder.Header().Get("Vary"), "Accept-Encoding", "Vary header is set")
+	assert.Equal(t, string(body), largeBody, "Body is decoded")
+}
+
+func TestCompressionDeflatePreferredByQuality(t *testing.T) {
+	reset()
+	registerCompressedBody("application/json", largeBody)
+
+	recorder := getCompressed("gzip;q=0.5, deflate")
+	reader, _ := zlib.NewReader(recorder.Body)
+	body, _ := ioutil.ReadAll(reader)
+
+	assert.Equal(t, recorder.Header().Get("Content-Encoding"), "deflate", "Body is deflate encoded")
+	assert.Equal(t, string(body), largeBody, "Body is decoded")
+}
+
+func TestCompressionRejectedCodings(t *testing.T) {
+	reset()
+	registerCompressedBody("application/json", largeBody)
+
+	recorder := getCompressed("gzip;q=0, *;q=0")
+
+	assert.Equal(t, recorder.Header().Get("Content-Encoding"), "", "Body is not encoded")
+	assert.Equal(t, recorder.Body.String(), largeBody, "Body is sent as is")
+}
+
+func TestCompressionSkipsSmallBodies(t *testing.T) {
+	reset()
+	registerCompressedBody("application/json", `{"firstName":"Mario"}`)
+
+	recorder := getCompressed("gzip")
+
+	assert.Equal(t, recorder.Header().Get("Content-Encoding"), "", "Body is not encoded")
+	assert.Equal(t, recorder.Header().Get("Vary"), "Accept-Encoding", "Vary header is set")
+}
+
+func TestCompressionSkipsContentTypesNotAllowed(t *testing.T) {
+	reset()
+	registerCompressedBody("image/png", largeBody)
+
+	recorder := getCompressed("gzip")
+
+	assert.Equal(t, recorder.Header().Get("Content-Encoding"), "", "Body is not encoded")
+	assert.Equal(t, recorder.Header().Get("Vary"), "", "Vary header is not set")
+}
+
+func TestCompressionSniffsContentType(t *testing.T) {
+	reset()
+	registerCompressedBody("", strings.Repeat("Mario ", 200))
+
+	recorder := getCompressed("gzip")
+
+	assert.Equal(t, recorder.Header().Get("Content-Encoding"), "gzip", "Text body is encoded")
+}
+
+func TestCompressionSkipsEncodedBodies(t *testing.T) {
+	reset()
+	Intercept("/.*", NewCompression().Intercept)
+	Get("/persons", func(req RequestEntity, resp *ResponseEntity) {
+		resp.Status = 200
+		resp.Header = map[string]string{"Content-Type": "text/plain", "Content-Encoding": "br"}
+		resp.Body = []byte(largeBody)
+	})
+
+	recorder := getCompressed("gzip")
+
+	assert.Equal(t, recorder.Header().Get("Content-Encoding"), "br", "Encoding is kept")
+	assert.Equal(t, recorder.Body.String(), largeBody, "Body is sent as is")
+}
+
+func TestCompressionPluggableEncoder(t *testing.T) {
+	reset()
+	Intercept("/.*", NewCompression().Use(reverseEncoder{}).Intercept)
+	Get("/persons", func(req RequestEntity, resp *ResponseEntity) {
+		resp.Status = 200
+		resp.Header = map[string]string{"Content-Type": "application/json"}
+		resp.Body = []byte(largeBody)
+	})
+
+	recorder := getCompressed("gzip, reverse")
+
+	assert.Equal(t, recorder.Header().Get("Content-Encoding"), "reverse", "Preferred encoder is used")
+}
+
+type reverseEncoder struct{}
+
+func (r reverseEncoder) Encoding() string {
+	return "reverse"
+}
+
+func (r reverseEncoder) Encode(body []byte) ([]byte, error) {
+	reversed := make([]byte, len(body))
+
+	for i, b := range body {
+		reversed[len(body)-1-i] = b
+	}
+
+	return reversed, nil
+}
+
+func registerCompressedBody(contentType string, body string) {
+	Intercept("/.*", NewCompression().Intercept)
+	Get("/persons", func(req RequestEntity, resp *ResponseEntity) {
+		resp.Status = 200
+		resp.Header = map[string]string{}
+
+		if len(contentType) > 0 {
+			resp.Header["Content-Type"] = contentType
+		}
+
+		resp.Body = []byte(body)
+	})
+}
+
+func getCompressed(acceptEncoding string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(http.MethodGet, "/persons", strings.NewReader(""))
+	request.Header.Set("Accept-Encoding", acceptEncoding)
+	recorder := httptest.NewRecorder()
+
+	HandleRequest(recorder, request)
+
+	return recorder
+}
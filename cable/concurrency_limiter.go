@@ -0,0 +1,209 @@
+package cable
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type Priority int
+
+const (
+	PriorityLow Priority = iota
+	PriorityNormal
+	PriorityHigh
+	// PriorityCritical requests are never queued nor shed, e.g. health checks
+	PriorityCritical
+)
+
+// AdaptiveLimit adjusts the number of requests in flight to the observed latency.
+// The limit increases additively while requests are faster than TargetLatency and
+// decreases multiplicatively by Backoff if they are slower.
+type AdaptiveLimit struct {
+	MinLimit      int
+	MaxLimit      int
+	TargetLatency time.Duration
+	Backoff       float64
+}
+
+type ConcurrencyLimiter struct {
+	// MaxInFlight is the number of requests handled at once, the initial limit if Adaptive is set
+	MaxInFlight int
+	// MaxQueue requests wait for a slot, further requests are shed
+	MaxQueue int
+	// QueueTimeout is the longest time a request waits for a slot
+	QueueTimeout time.Duration
+	// RetryAfter is sent to clients whose request was shed
+	RetryAfter time.Duration
+	// Priority classifies requests, all requests have PriorityNormal if nil
+	Priority func(request *http.Request) Priority
+	Adaptive *AdaptiveLimit
+	mutex    sync.Mutex
+	inFlight int
+	limit    float64
+	// queues holds the waiting requests per priority below PriorityCritical
+	queues [PriorityCritical][]*waiter
+}
+
+type waiter struct {
+	ready   chan struct{}
+	granted bool
+}
+
+// NewConcurrencyLimiter caps in flight requests of the routes it is registered for with
+// cable.Intercept(pattern, limiter.Intercept)
+func NewConcurrencyLimiter(maxInFlight int, maxQueue int) *ConcurrencyLimiter {
+	return &ConcurrencyLimiter{
+		MaxInFlight:  maxInFlight,
+		MaxQueue:     maxQueue,
+		QueueTimeout: time.Second,
+		RetryAfter:   time.Second}
+}
+
+func (c *ConcurrencyLimiter) Intercept(requestEntity RequestEntity, response *ResponseEntity, next func(requestEntity RequestEntity, response *ResponseEntity)) {
+	priority := PriorityNormal
+
+	if c.Priority != nil {
+		priority = c.Priority(requestEntity.Request)
+	}
+
+	if priority >= PriorityCritical {
+		next(requestEntity, response)
+		return
+	}
+
+	if !c.acquire(requestEntity.Request, priority) {
+		logger.Info("Request shed",
+			zap.String("Path", requestEntity.Request.URL.Path),
+			zap.Int("Priority", int(priority)))
+
+		setError(response, requestEntity.Request, http.StatusServiceUnavailable, "too many requests in flight")
+		response.SetHeader(retryAfterHeader, strconv.Itoa(ceilSeconds(c.RetryAfter)))
+		return
+	}
+
+	start := time.Now()
+	defer func() {
+		c.release(time.Since(start))
+	}()
+
+	next(requestEntity, response)
+}
+
+func (c *ConcurrencyLimiter) acquire(request *http.Request, priority Priority) bool {
+	c.mutex.Lock()
+
+	if c.limit == 0 {
+		c.limit = float64(c.MaxInFlight)
+	}
+
+	if float64(c.inFlight) < math.Floor(c.limit) && c.queued() == 0 {
+		c.inFlight++
+		c.mutex.Unlock()
+		return true
+	}
+
+	if c.queued() >= c.MaxQueue && !c.shedLowerThan(priority) {
+		c.mutex.Unlock()
+		return false
+	}
+
+	w := &waiter{ready: make(chan struct{})}
+	c.queues[priority] = append(c.queues[priority], w)
+	c.mutex.Unlock()
+
+	timer := time.NewTimer(c.QueueTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-w.ready:
+	case <-timer.C:
+	case <-request.Context().Done():
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if !w.granted {
+		c.remove(priority, w)
+	}
+
+	return w.granted
+}
+
+// shedLowerThan makes room in the queue by rejecting the latest request of a lower priority
+func (c *ConcurrencyLimiter) shedLowerThan(priority Priority) bool {
+	for p := PriorityLow; p < priority; p++ {
+		if queue := c.queues[p]; len(queue) > 0 {
+			c.queues[p] = queue[:len(queue)-1]
+			close(queue[len(queue)-1].ready)
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c *ConcurrencyLimiter) remove(priority Priority, w *waiter) {
+	queue := c.queues[priority]
+
+	for i, queued := range queue {
+		if queued == w {
+			c.queues[priority] = append(queue[:i], queue[i+1:]...)
+			return
+		}
+	}
+}
+
+func (c *ConcurrencyLimiter) queued() int {
+	queued := 0
+
+	for _, queue := range c.queues {
+		queued += len(queue)
+	}
+
+	return queued
+}
+
+func (c *ConcurrencyLimiter) release(latency time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.inFlight--
+
+	if a := c.Adaptive; a != nil {
+		if latency <= a.TargetLatency {
+			c.limit += 1 / c.limit
+		} else {
+			c.limit *= a.Backoff
+		}
+
+		c.limit = math.Max(math.Max(1, float64(a.MinLimit)), math.Min(float64(a.MaxLimit), c.limit))
+	}
+
+	for p := PriorityCritical - 1; p >= PriorityLow; p-- {
+		for len(c.queues[p]) > 0 && float64(c.inFlight) < math.Floor(c.limit) {
+			w := c.queues[p][0]
+			c.queues[p] = c.queues[p][1:]
+			w.granted = true
+			c.inFlight++
+			close(w.ready)
+		}
+	}
+}
+
+// Limit is the current number of requests allowed in flight
+func (c *ConcurrencyLimiter) Limit() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.limit == 0 {
+		return c.MaxInFlight
+	}
+
+	return int(c.limit)
+}
@@ -0,0 +1,175 @@
+package cable
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	assert "github.com/stfsy/golang-assert"
+)
+
+func TestConcurrencyLimitShedsWith503(t *testing.T) {
+	reset()
+	release := make(chan bool)
+	limiter := NewConcurrencyLimiter(1, 0)
+	registerBlockingHandler(limiter, release)
+
+	first := getBlockingAsync()
+	waitForInFlight(limiter, 1)
+	recorder := getBlocking("/blocking")
+	close(release)
+	<-first
+
+	assert.Equal(t, recorder.Code, 503, "Response status code is 503")
+	assert.Equal(t, recorder.Header().Get("Retry-After"), "1", "Retry-After is 1 second")
+}
+
+func TestConcurrencyLimitShedsUnacceptableRequestsWithoutBody(t *testing.T) {
+	reset()
+	release := make(chan bool)
+	limiter := NewConcurrencyLimiter(1, 0)
+	registerBlockingHandler(limiter, release)
+
+	first := getBlockingAsync()
+	waitForInFlight(limiter, 1)
+	request := httptest.NewRequest(http.MethodGet, "/blocking", strings.NewReader(""))
+	request.Header.Set("Accept", "image/png")
+	recorder := httptest.NewRecorder()
+	HandleRequest(recorder, request)
+	close(release)
+	<-first
+
+	assert.Equal(t, recorder.Code, 503, "Response status code is 503")
+	assert.Equal(t, recorder.Header().Get("Retry-After"), "1", "Retry-After is 1 second")
+	assert.Equal(t, recorder.Body.Len(), 0, "Response has no body")
+}
+
+func TestConcurrencyLimitQueuesRequests(t *testing.T) {
+	reset()
+	release := make(chan bool)
+	limiter := NewConcurrencyLimiter(1, 1)
+	registerBlockingHandler(limiter, release)
+
+	first := getBlockingAsync()
+	waitForInFlight(limiter, 1)
+	second := getBlockingAsync()
+	waitForQueued(limiter, 1)
+	close(release)
+
+	assert.Equal(t, (<-first).Code, 200, "Response status code of first request is 200")
+	assert.Equal(t, (<-second).Code, 200, "Response status code of queued request is 200")
+}
+
+func TestConcurrencyLimitNeverShedsCriticalRequests(t *testing.T) {
+	reset()
+	release := make(chan bool)
+	limiter := NewConcurrencyLimiter(1, 0)
+	limiter.Priority = func(request *http.Request) Priority {
+		if request.URL.Path == "/health" {
+			return PriorityCritical
+		}
+		return PriorityNormal
+	}
+	registerBlockingHandler(limiter, release)
+	Get("/health", func(req RequestEntity, resp *ResponseEntity) {
+		resp.Status = 200
+	})
+
+	first := getBlockingAsync()
+	waitForInFlight(limiter, 1)
+	recorder := getBlocking("/health")
+	close(release)
+	<-first
+
+	assert.Equal(t, recorder.Code, 200, "Response status code is 200")
+}
+
+func TestConcurrencyLimitShedsLowerPriorityFromQueue(t *testing.T) {
+	limiter := NewConcurrencyLimiter(1, 1)
+	request := httptest.NewRequest(http.MethodGet, "/blocking", strings.NewReader(""))
+
+	limiter.acquire(request, PriorityNormal)
+	low := make(chan bool)
+	go func() { low <- limiter.acquire(request, PriorityLow) }()
+	waitForQueued(limiter, 1)
+	high := make(chan bool)
+	go func() { high <- limiter.acquire(request, PriorityHigh) }()
+
+	assert.Equal(t, <-low, false, "Low priority request is shed")
+	waitForQueued(limiter, 1)
+	limiter.release(0)
+	assert.Equal(t, <-high, true, "High priority request is handled")
+}
+
+func TestAdaptiveLimitBacksOffOnHighLatency(t *testing.T) {
+	limiter := NewConcurrencyLimiter(10, 0)
+	limiter.Adaptive = &AdaptiveLimit{MinLimit: 2, MaxLimit: 20, TargetLatency: 100 * time.Millisecond, Backoff: 0.5}
+	request := httptest.NewRequest(http.MethodGet, "/blocking", strings.NewReader(""))
+
+	limiter.acquire(request, PriorityNormal)
+	limiter.release(time.Second)
+	assert.Equal(t, limiter.Limit(), 5, "Limit is halved")
+
+	limiter.acquire(request, PriorityNormal)
+	limiter.release(time.Second)
+	limiter.acquire(request, PriorityNormal)
+	limiter.release(time.Second)
+	assert.Equal(t, limiter.Limit(), 2, "Limit does not fall below MinLimit")
+
+	for i := 0; i < 4; i++ {
+		limiter.acquire(request, PriorityNormal)
+		limiter.release(time.Millisecond)
+	}
+	assert.Equal(t, limiter.Limit(), 3, "Limit increases additively")
+}
+
+func registerBlockingHandler(limiter *ConcurrencyLimiter, release chan bool) {
+	Intercept("/.*", limiter.Intercept)
+	Get("/blocking", func(req RequestEntity, resp *ResponseEntity) {
+		<-release
+		resp.Status = 200
+	})
+}
+
+func getBlocking(path string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(http.MethodGet, path, strings.NewReader(""))
+	recorder := httptest.NewRecorder()
+
+	HandleRequest(recorder, request)
+
+	return recorder
+}
+
+func getBlockingAsync() chan *httptest.ResponseRecorder {
+	done := make(chan *httptest.ResponseRecorder, 1)
+
+	go func() {
+		done <- getBlocking("/blocking")
+	}()
+
+	return done
+}
+
+func waitForInFlight(limiter *ConcurrencyLimiter, inFlight int) {
+	waitFor(func() bool { return limiter.inFlight == inFlight }, limiter)
+}
+
+func waitForQueued(limiter *ConcurrencyLimiter, queued int) {
+	waitFor(func() bool { return limiter.queued() == queued }, limiter)
+}
+
+func waitFor(condition func() bool, limiter *ConcurrencyLimiter) {
+	for i := 0; i < 1000; i++ {
+		limiter.mutex.Lock()
+		done := condition()
+		limiter.mutex.Unlock()
+
+		if done {
+			return
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,205 @@
+package cable
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	etagHeader         = "Etag"
+	lastModifiedHeader = "Last-Modified"
+	cacheControlHeader = "Cache-Control"
+)
+
+// CacheControl is rendered to the Cache-Control header of a response. Zero values are omitted.
+//
+// https://tools.ietf.org/html/rfc7234#section-5.2.2
+type CacheControl struct {
+	MaxAge               time.Duration
+	SharedMaxAge         time.Duration
+	StaleWhileRevalidate time.Duration
+	Public               bool
+	Private              bool
+	NoCache              bool
+	NoStore              bool
+	MustRevalidate       bool
+	Immutable            bool
+}
+
+func (c CacheControl) String() string {
+	directives := []string{}
+
+	for _, flag := range []struct {
+		set       bool
+		directive string
+	}{
+		{c.Public, "public"},
+		{c.Private, "private"},
+		{c.NoCache, "no-cache"},
+		{c.NoStore, "no-store"},
+		{c.MustRevalidate, "must-revalidate"},
+		{c.Immutable, "immutable"}} {
+		if flag.set {
+			directives = append(directives, flag.directive)
+		}
+	}
+
+	for _, age := range []struct {
+		duration  time.Duration
+		directive string
+	}{
+		{c.MaxAge, "max-age"},
+		{c.SharedMaxAge, "s-maxage"},
+		{c.StaleWhileRevalidate, "stale-while-revalidate"}} {
+		if age.duration > 0 {
+			directives = append(directives, fmt.Sprintf("%s=%d", age.directive, int64(age.duration/time.Second)))
+		}
+	}
+
+	return strings.Join(directives, ", ")
+}
+
+// SetHeader sets a response header, creating the header map if necessary
+func (r *ResponseEntity) SetHeader(key string, value string) {
+	if r.Header == nil {
+		r.Header = map[string]string{}
+	}
+
+	r.Header[textproto.CanonicalMIMEHeaderKey(key)] = value
+}
+
+// SetETag sets the entity tag of the response, e.g. a version number. Weak tags mark
+// representations that are semantically but not byte for byte equivalent.
+func (r *ResponseEntity) SetETag(tag string, weak bool) {
+	etag := `"` + tag + `"`
+
+	if weak {
+		etag = "W/" + etag
+	}
+
+	r.SetHeader(etagHeader, etag)
+}
+
+// SetCookie adds a Set-Cookie header to the response
+func (r *ResponseEntity) SetCookie(cookie *http.Cookie) {
+	r.Cookies = append(r.Cookies, cookie)
+}
+
+func (r *ResponseEntity) SetLastModified(modified time.Time) {
+	r.SetHeader(lastModifiedHeader, modified.UTC().Format(http.TimeFormat))
+}
+
+func (r *ResponseEntity) SetCacheControl(cacheControl CacheControl) {
+	r.SetHeader(cacheControlHeader, cacheControl.String())
+}
+
+// ConditionalGet returns an interceptor answering GET and HEAD requests with 304 Not
+// Modified if the client's copy is current. Responses without ETag set by the handler
+// get one computed from the body. Register it before cable.Compression so tags of
+// encoded representations are validated, with cable.Intercept(pattern, cable.ConditionalGet(weak)).
+func ConditionalGet(weak bool) func(requestEntity RequestEntity, response *ResponseEntity, next func(requestEntity RequestEntity, response *ResponseEntity)) {
+	return func(requestEntity RequestEntity, response *ResponseEntity, next func(requestEntity RequestEntity, response *ResponseEntity)) {
+		next(requestEntity, response)
+
+		request := requestEntity.Request
+
+		if (request.Method != http.MethodGet && request.Method != http.MethodHead) || response.Status != http.StatusOK {
+			return
+		}
+
+		if len(response.Header[etagHeader]) == 0 && len(response.Body) > 0 {
+			digest := sha256.Sum256(response.Body)
+			response.SetETag(base64.RawURLEncoding.EncodeToString(digest[:16]), weak)
+		}
+
+		if isNotModified(request, response) {
+			logger.Debug("Not modified",
+				zap.String("Path", request.URL.Path),
+				zap.String("ETag", response.Header[etagHeader]))
+
+			response.Status = http.StatusNotModified
+			response.Body = nil
+			delete(response.Header, contentTypeHeader)
+			delete(response.Header, "Content-Length")
+		}
+	}
+}
+
+/*
+* A recipient MUST ignore If-Modified-Since if the request contains an If-None-Match
+* header field; the condition in If-None-Match is considered to be a more accurate
+* replacement for the condition in If-Modified-Since.
+*
+* A recipient MUST use the weak comparison function when comparing entity-tags for
+* If-None-Match.
+*
+* https://tools.ietf.org/html/rfc7232#section-3.3
+ */
+func isNotModified(request *http.Request, response *ResponseEntity) bool {
+	if ifNoneMatch := request.Header.Get("If-None-Match"); len(ifNoneMatch) > 0 {
+		etag := response.Header[etagHeader]
+
+		for _, tag := range parseETags(ifNoneMatch) {
+			if tag == "*" || (len(etag) > 0 && weakMatch(tag, etag)) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	modifiedSince, err := http.ParseTime(request.Header.Get("If-Modified-Since"))
+	lastModified, lastModifiedErr := http.ParseTime(response.Header[lastModifiedHeader])
+
+	if err != nil || lastModifiedErr != nil {
+		return false
+	}
+
+	return !lastModified.After(modifiedSince)
+}
+
+// parseETags splits a list of entity tags. Tags may contain commas, so the list is scanned
+// for quoted strings.
+func parseETags(value string) []string {
+	tags := []string{}
+
+	for value = strings.TrimSpace(value); len(value) > 0; value = strings.TrimLeft(value, ", \t") {
+		if value[0] == '*' {
+			tags = append(tags, "*")
+			value = value[1:]
+			continue
+		}
+
+		start := 0
+
+		if strings.HasPrefix(value, "W/") {
+			start = 2
+		}
+
+		if len(value) <= start || value[start] != '"' {
+			break
+		}
+
+		end := strings.IndexByte(value[start+1:], '"')
+
+		if end < 0 {
+			break
+		}
+
+		tags = append(tags, value[:start+end+2])
+		value = value[start+end+2:]
+	}
+
+	return tags
+}
+
+func weakMatch(a string, b string) bool {
+	return strings.TrimPrefix(a, "W/") == strings.TrimPrefix(b, "W/")
+}
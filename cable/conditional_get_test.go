@@ -0,0 +1,124 @@
+package cable
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	assert "github.com/stfsy/golang-assert"
+)
+
+func TestConditionalGetComputesETag(t *testing.T) {
+	reset()
+	Intercept("/.*", ConditionalGet(false))
+	registerGetPersonsBody()
+
+	first := getConditional(nil)
+	second := getConditional(map[string]string{"If-None-Match": first.Header().Get("ETag")})
+
+	assert.Equal(t, first.Code, 200, "Response status code is 200")
+	assert.Equal(t, strings.HasPrefix(first.Header().Get("ETag"), `"`), true, "Strong ETag is set")
+	assert.Equal(t, second.Code, 304, "Response status code is 304")
+	assert.Equal(t, second.Body.String(), "", "Response has no body")
+	assert.Equal(t, second.Header().Get("ETag"), first.Header().Get("ETag"), "ETag is sent again")
+}
+
+func TestConditionalGetWeakETagMatchesWeakly(t *testing.T) {
+	reset()
+	Intercept("/.*", ConditionalGet(true))
+	Get("/persons", func(req RequestEntity, resp *ResponseEntity) {
+		resp.Status = 200
+		resp.Body = []byte("Persons")
+		resp.SetETag("v42", false)
+	})
+
+	recorder := getConditional(map[string]string{"If-None-Match": `"v41", W/"v42"`})
+
+	assert.Equal(t, recorder.Code, 304, "Response status code is 304")
+}
+
+func TestConditionalGetChangedETagResponds200(t *testing.T) {
+	reset()
+	Intercept("/.*", ConditionalGet(false))
+	registerGetPersonsBody()
+
+	recorder := getConditional(map[string]string{"If-None-Match": `"outdated"`})
+
+	assert.Equal(t, recorder.Code, 200, "Response status code is 200")
+}
+
+func TestConditionalGetIfModifiedSince(t *testing.T) {
+	reset()
+	Intercept("/.*", ConditionalGet(false))
+	Get("/persons", func(req RequestEntity, resp *ResponseEntity) {
+		resp.Status = 200
+		resp.Body = []byte("Persons")
+		resp.SetLastModified(epoch)
+		resp.SetCacheControl(CacheControl{Private: true, MaxAge: time.Minute})
+	})
+
+	current := getConditional(map[string]string{"If-Modified-Since": epoch.Format(http.TimeFormat)})
+	outdated := getConditional(map[string]string{"If-Modified-Since": epoch.Add(-time.Hour).Format(http.TimeFormat)})
+
+	assert.Equal(t, current.Code, 304, "Response status code is 304")
+	assert.Equal(t, current.Header().Get("Cache-Control"), "private, max-age=60", "Cache-Control is set")
+	assert.Equal(t, outdated.Code, 200, "Response status code is 200")
+}
+
+func TestConditionalGetIgnoresUnsafeMethods(t *testing.T) {
+	reset()
+	Intercept("/.*", ConditionalGet(false))
+	registerPostPersons()
+
+	request := httptest.NewRequest(http.MethodPost, "/persons", strings.NewReader("[]"))
+	request.Header.Set("If-None-Match", "*")
+	recorder := httptest.NewRecorder()
+
+	HandleRequest(recorder, request)
+
+	assert.Equal(t, recorder.Code, 200, "Response status code is 200")
+}
+
+func TestConditionalGetUsesHandlerETagInAnyCase(t *testing.T) {
+	reset()
+	Intercept("/.*", ConditionalGet(false))
+	Get("/persons", func(req RequestEntity, resp *ResponseEntity) {
+		resp.Status = 200
+		resp.Body = []byte("Persons")
+		resp.Header = map[string]string{"ETag": `"v42"`}
+	})
+
+	first := getConditional(nil)
+	second := getConditional(map[string]string{"If-None-Match": `"v42"`})
+
+	assert.Equal(t, first.Header()["Etag"], []string{`"v42"`}, "Handler ETag is sent once")
+	assert.Equal(t, second.Code, 304, "Response status code is 304")
+}
+
+func TestParseETags(t *testing.T) {
+	assert.Equal(t, parseETags(`"a", W/"b,c" ,"d"`), []string{`"a"`, `W/"b,c"`, `"d"`}, "Tags are split")
+	assert.Equal(t, parseETags(`*`), []string{"*"}, "Wildcard is parsed")
+}
+
+func registerGetPersonsBody() {
+	Get("/persons", func(req RequestEntity, resp *ResponseEntity) {
+		resp.Status = 200
+		resp.Body = []byte("Some Persons")
+	})
+}
+
+func getConditional(header map[string]string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(http.MethodGet, "/persons", strings.NewReader(""))
+
+	for key, value := range header {
+		request.Header.Set(key, value)
+	}
+
+	recorder := httptest.NewRecorder()
+
+	HandleRequest(recorder, request)
+
+	return recorder
+}
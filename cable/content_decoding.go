@@ -0,0 +1,119 @@
+package cable
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// ContentDecoder decodes a request body sent with a content coding, e.g. gzip
+type ContentDecoder func(body io.Reader) (io.ReadCloser, error)
+
+var (
+	// ErrUnsupportedEncoding is returned when a request body has a content coding without decoder
+	ErrUnsupportedEncoding = errors.New("unsupported content encoding")
+
+	defaultContentDecoders = map[string]ContentDecoder{
+		"gzip":    decodeGzip,
+		"x-gzip":  decodeGzip,
+		"deflate": decodeDeflate}
+	contentDecoders = copyContentDecoders(defaultContentDecoders)
+)
+
+// RegisterContentDecoder decodes request bodies sent with encoding, e.g. br or zstd
+// backed by third party libraries
+func RegisterContentDecoder(encoding string, decoder ContentDecoder) {
+	contentDecoders[strings.ToLower(encoding)] = decoder
+
+	logger.Info("Registered Content Decoder",
+		zap.String("Encoding", encoding))
+}
+
+func decodeGzip(body io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(body)
+}
+
+func decodeDeflate(body io.Reader) (io.ReadCloser, error) {
+	return zlib.NewReader(body)
+}
+
+func copyContentDecoders(decoders map[string]ContentDecoder) map[string]ContentDecoder {
+	copied := make(map[string]ContentDecoder, len(decoders))
+
+	for encoding, decoder := range decoders {
+		copied[encoding] = decoder
+	}
+
+	return copied
+}
+
+/*
+* If one or more encodings have been applied to a representation, the sender that
+* applied the encodings MUST generate a Content-Encoding header field that lists the
+* content codings in the order in which they were applied.
+*
+* An origin server MAY respond with a status code of 415 (Unsupported Media Type) if a
+* representation in the request message has a content coding that is not acceptable.
+*
+* https://tools.ietf.org/html/rfc7231#section-3.1.2.2
+ */
+func decodeBody(writer http.ResponseWriter, request *http.Request) bool {
+	encodings := []string{}
+
+	for _, value := range request.Header.Values(contentEncodingHeader) {
+		for _, encoding := range strings.Split(value, ",") {
+			encoding = strings.ToLower(strings.TrimSpace(encoding))
+
+			if len(encoding) > 0 && encoding != "identity" {
+				encodings = append(encodings, encoding)
+			}
+		}
+	}
+
+	if len(encodings) == 0 {
+		return true
+	}
+
+	body := ioutil.NopCloser(request.Body)
+
+	for i := len(encodings) - 1; i >= 0; i-- {
+		decoder, ok := contentDecoders[encodings[i]]
+
+		if !ok {
+			logger.Info("Unsupported content encoding",
+				zap.String("Path", request.URL.Path),
+				zap.String("Encoding", encodings[i]))
+
+			writeError(writer, request, http.StatusUnsupportedMediaType, ErrUnsupportedEncoding.Error())
+			return false
+		}
+
+		decoded, err := decoder(body)
+
+		if err != nil {
+			logger.Info("Decoding request body failed",
+				zap.String("Path", request.URL.Path),
+				zap.String("Encoding", encodings[i]),
+				zap.Error(err))
+
+			writeError(writer, request, http.StatusBadRequest, "request body could not be decoded")
+			return false
+		}
+
+		body = decoded
+	}
+
+	// the original body is still closed by the server, and limits apply to the decoded size
+	request.Body = body
+	request.ContentLength = -1
+	request.Header.Del(contentEncodingHeader)
+	request.Header.Del("Content-Length")
+
+	return true
+}
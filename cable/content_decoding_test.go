@@ -0,0 +1,104 @@
+package cable
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	assert "github.com/stfsy/golang-assert"
+)
+
+func TestContentDecodingGzip(t *testing.T) {
+	reset()
+	registerPostRawBody()
+
+	recorder := postEncoded(gzipped(jsonBody), "gzip")
+
+	assert.Equal(t, recorder.Code, 200, "Response status code is 200")
+	assert.Equal(t, recorder.Body.String(), jsonBody, "Handler reads the decoded body")
+}
+
+func TestContentDecodingUnmarshal(t *testing.T) {
+	reset()
+	registerPostPersonUnmarshal()
+
+	recorder := postEncoded(gzipped(jsonBody), "gzip")
+
+	assert.Equal(t, recorder.Code, 200, "Response status code is 200")
+}
+
+func TestContentDecodingUnknownEncodingResponds415(t *testing.T) {
+	reset()
+	registerPostRawBody()
+
+	recorder := postEncoded([]byte(jsonBody), "br")
+
+	assert.Equal(t, recorder.Code, 415, "Response status code is 415")
+	assert.Equal(t, recorder.Body.String(), `{"type":"about:blank","title":"Unsupported Media Type","status":415,"detail":"unsupported content encoding","instance":"/persons"}`, "Response body describes the error")
+}
+
+func TestContentDecodingCorruptBodyResponds400(t *testing.T) {
+	reset()
+	registerPostRawBody()
+
+	recorder := postEncoded([]byte(jsonBody), "gzip")
+
+	assert.Equal(t, recorder.Code, 400, "Response status code is 400")
+}
+
+func TestContentDecodingLimitsDecodedSize(t *testing.T) {
+	reset()
+	SetBodyLimit(BodyLimit{JSON: 1024})
+	registerPostPersonUnmarshal()
+
+	// compresses to a few hundred bytes
+	recorder := postEncoded(gzipped(strings.Repeat(" ", 1<<20)+jsonBody), "gzip")
+
+	assert.Equal(t, recorder.Code, 413, "Response status code is 413")
+}
+
+func TestContentDecodingPluggableDecoder(t *testing.T) {
+	reset()
+	RegisterContentDecoder("br", func(body io.Reader) (io.ReadCloser, error) {
+		return ioutil.NopCloser(body), nil
+	})
+	registerPostRawBody()
+
+	recorder := postEncoded([]byte(jsonBody), "br")
+
+	assert.Equal(t, recorder.Code, 200, "Response status code is 200")
+	assert.Equal(t, recorder.Body.String(), jsonBody, "Handler reads the decoded body")
+}
+
+func registerPostRawBody() {
+	Post("/persons", func(req RequestEntity, resp *ResponseEntity) {
+		body, _ := ioutil.ReadAll(req.Request.Body)
+		resp.Status = 200
+		resp.Body = body
+	})
+}
+
+func postEncoded(body []byte, encoding string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(http.MethodPost, "/persons", bytes.NewReader(body))
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Content-Encoding", encoding)
+	recorder := httptest.NewRecorder()
+
+	HandleRequest(recorder, request)
+
+	return recorder
+}
+
+func gzipped(body string) []byte {
+	buffer := bytes.Buffer{}
+	writer := gzip.NewWriter(&buffer)
+	writer.Write([]byte(body))
+	writer.Close()
+
+	return buffer.Bytes()
+}
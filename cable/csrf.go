@@ -0,0 +1,301 @@
+package cable
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+const (
+	csrfTokenLength = 32
+	// csrfMaxFieldLength is more than enough for a base64 nonce and signature
+	csrfMaxFieldLength = 1024
+)
+
+// CSRF protects unsafe methods with signed double submit cookies. The token is set as
+// a cookie and must be sent back in a header or form field by the client.
+//
+// https://cheatsheetseries.owasp.org/cheatsheets/Cross-Site_Request_Forgery_Prevention_Cheat_Sheet.html
+type CSRF struct {
+	Secret     []byte
+	CookieName string
+	HeaderName string
+	FieldName  string
+	// TrustedOrigins may send unsafe requests in addition to the origin of the request itself,
+	// e.g. https://app.example.com
+	TrustedOrigins []string
+	// Secure limits the cookie to https
+	Secure bool
+	// Binding identifies the client a token is issued to, tokens of other clients are
+	// rejected. It defaults to the principal, so register authentication before CSRF.
+	// Tokens of anonymous clients are not bound and only protected by the origin check.
+	Binding func(request *http.Request) string
+	exempt  []*regexp.Regexp
+}
+
+// NewCSRF signs tokens with secret. Register it with cable.Filter(pattern, csrf.Filter).
+func NewCSRF(secret []byte) *CSRF {
+	return &CSRF{
+		Secret:     secret,
+		CookieName: "csrf_token",
+		HeaderName: "X-CSRF-Token",
+		FieldName:  "csrf_token",
+		Secure:     true,
+		Binding:    principalBinding}
+}
+
+func principalBinding(request *http.Request) string {
+	principal := PrincipalOf(request)
+
+	if principal == nil {
+		return ""
+	}
+
+	return principal.Scheme + "\n" + principal.Name
+}
+
+// Exempt skips checks for routes not used by browsers, e.g. webhooks
+func (c *CSRF) Exempt(patterns ...string) *CSRF {
+	for _, pattern := range patterns {
+		c.exempt = append(c.exempt, stringToRegex(pattern))
+	}
+
+	return c
+}
+
+func (c *CSRF) Filter(writer http.ResponseWriter, request *http.Request) {
+	token, valid := c.tokenFromCookie(request)
+
+	if !valid {
+		token = c.newToken(request)
+		http.SetCookie(writer, &http.Cookie{
+			Name:     c.CookieName,
+			Value:    token,
+			Path:     "/",
+			Secure:   c.Secure,
+			SameSite: http.SameSiteLaxMode})
+	}
+
+	scope := scopeOf(request)
+	scope.csrfToken = token
+	scope.csrfField = c.FieldName
+
+	if isSafeMethod(request.Method) || c.isExempt(request.URL.Path) {
+		return
+	}
+
+	if !c.isSameOrigin(request) {
+		c.reject(writer, request, "origin does not match")
+		return
+	}
+
+	submitted := request.Header.Get(c.HeaderName)
+
+	if len(submitted) == 0 {
+		if !prepareBody(writer, request) {
+			return
+		}
+
+		var err error
+		submitted, err = c.tokenFromForm(request)
+
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError) {
+			writeError(writer, request, http.StatusRequestEntityTooLarge, ErrBodyTooLarge.Error())
+			return
+		}
+	}
+
+	if !valid || subtle.ConstantTimeCompare([]byte(submitted), []byte(token)) != 1 {
+		c.reject(writer, request, "token does not match")
+	}
+}
+
+// tokenFromForm reads the form field without consuming the body, so request handlers
+// still see the complete body. Multipart bodies are only read up to the field and
+// files are never stored.
+func (c *CSRF) tokenFromForm(request *http.Request) (string, error) {
+	original := request.Body
+	consumed := &bytes.Buffer{}
+	body := io.TeeReader(original, consumed)
+
+	defer func() {
+		request.Body = replayedBody{io.MultiReader(consumed, original), original}
+	}()
+
+	mediatype, params, _ := mime.ParseMediaType(request.Header.Get(contentTypeHeader))
+
+	switch mediatype {
+	case "application/x-www-form-urlencoded":
+		form, err := ioutil.ReadAll(body)
+
+		if err != nil {
+			return "", err
+		}
+
+		values, _ := url.ParseQuery(string(form))
+
+		return values.Get(c.FieldName), nil
+	case "multipart/form-data":
+		reader := multipart.NewReader(body, params["boundary"])
+
+		for {
+			part, err := reader.NextPart()
+
+			if err != nil {
+				return "", err
+			}
+
+			if part.FormName() == c.FieldName && len(part.FileName()) == 0 {
+				token, err := ioutil.ReadAll(io.LimitReader(part, csrfMaxFieldLength))
+				return string(token), err
+			}
+		}
+	}
+
+	return "", nil
+}
+
+// replayedBody returns the bytes read ahead before the rest of the body
+type replayedBody struct {
+	io.Reader
+	io.Closer
+}
+
+// CSRFToken returns the token to submit with unsafe requests, e.g. in a meta tag
+func CSRFToken(request *http.Request) string {
+	return scopeOf(request).csrfToken
+}
+
+// CSRFField returns a hidden form field carrying the token for html templates
+func CSRFField(request *http.Request) template.HTML {
+	scope := scopeOf(request)
+
+	return template.HTML(fmt.Sprintf(`<input type="hidden" name="%s" value="%s">`,
+		template.HTMLEscapeString(scope.csrfField),
+		template.HTMLEscapeString(scope.csrfToken)))
+}
+
+func (c *CSRF) reject(writer http.ResponseWriter, request *http.Request, reason string) {
+	logger.Info("CSRF check failed",
+		zap.String("Path", request.URL.Path),
+		zap.String("Reason", reason))
+
+	writeError(writer, request, http.StatusForbidden, "csrf check failed")
+}
+
+func (c *CSRF) newToken(request *http.Request) string {
+	nonce := make([]byte, csrfTokenLength)
+	rand.Read(nonce)
+
+	return base64.RawURLEncoding.EncodeToString(nonce) + "." + c.sign(nonce, request)
+}
+
+// sign binds the nonce to the client, following the signed double submit cookie pattern
+func (c *CSRF) sign(nonce []byte, request *http.Request) string {
+	binding := ""
+
+	if c.Binding != nil {
+		binding = c.Binding(request)
+	}
+
+	mac := hmac.New(sha256.New, c.Secret)
+	mac.Write([]byte(strconv.Itoa(len(binding)) + ":" + binding))
+	mac.Write(nonce)
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// tokenFromCookie only accepts tokens signed by us for the client of the request. An
+// attacker can obtain a signed token with own credentials and plant it as the cookie of
+// a victim, e.g. from a sibling subdomain, but it is not valid for the victim.
+func (c *CSRF) tokenFromCookie(request *http.Request) (string, bool) {
+	cookie, err := request.Cookie(c.CookieName)
+
+	if err != nil {
+		return "", false
+	}
+
+	parts := strings.SplitN(cookie.Value, ".", 2)
+
+	if len(parts) != 2 {
+		return "", false
+	}
+
+	nonce, err := base64.RawURLEncoding.DecodeString(parts[0])
+
+	if err != nil || !hmac.Equal([]byte(parts[1]), []byte(c.sign(nonce, request))) {
+		return "", false
+	}
+
+	return cookie.Value, true
+}
+
+func (c *CSRF) isExempt(path string) bool {
+	for _, pattern := range c.exempt {
+		if len(pattern.FindString(path)) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c *CSRF) isSameOrigin(request *http.Request) bool {
+	origin := request.Header.Get("Origin")
+
+	if len(origin) == 0 {
+		referer, err := url.Parse(request.Header.Get("Referer"))
+
+		// neither header is sent, rely on the token alone
+		if err != nil || len(referer.Host) == 0 {
+			return true
+		}
+
+		origin = referer.Scheme + "://" + referer.Host
+	}
+
+	if strings.EqualFold(origin, Scheme(request)+"://"+Host(request)) {
+		return true
+	}
+
+	for _, trusted := range c.TrustedOrigins {
+		if strings.EqualFold(origin, trusted) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isSafeMethod(method string) bool {
+	/*
+	* Request methods are considered "safe" if their defined semantics are
+	* essentially read-only
+	*
+	* https://tools.ietf.org/html/rfc7231#section-4.2.1
+	 */
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+
+	return false
+}
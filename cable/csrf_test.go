@@ -0,0 +1,302 @@
+package cable
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	assert "github.com/stfsy/golang-assert"
+)
+
+func TestCSRFSetsTokenCookieOnSafeRequest(t *testing.T) {
+	reset()
+	registerCSRF()
+	Get("/form", func(req RequestEntity, resp *ResponseEntity) {
+		resp.Status = 200
+		resp.Body = []byte(CSRFField(req.Request))
+	})
+
+	request := httptest.NewRequest(http.MethodGet, "/form", strings.NewReader(""))
+	recorder := httptest.NewRecorder()
+
+	HandleRequest(recorder, request)
+
+	cookie := recorder.Result().Cookies()[0]
+
+	assert.Equal(t, recorder.Code, 200, "Response status code is 200")
+	assert.Equal(t, cookie.Name, "csrf_token", "Token cookie is set")
+	assert.Equal(t, recorder.Body.String(), `<input type="hidden" name="csrf_token" value="`+cookie.Value+`">`, "Form field carries the token")
+}
+
+func TestCSRFMissingTokenResponds403(t *testing.T) {
+	reset()
+	registerCSRF()
+	registerPostPersons()
+
+	request := httptest.NewRequest(http.MethodPost, "/persons", strings.NewReader("[]"))
+	request.AddCookie(&http.Cookie{Name: "csrf_token", Value: csrfToken()})
+	recorder := httptest.NewRecorder()
+
+	HandleRequest(recorder, request)
+
+	assert.Equal(t, recorder.Code, 403, "Response status code is 403")
+}
+
+func TestCSRFTokenInHeaderResponds200(t *testing.T) {
+	reset()
+	registerCSRF()
+	registerPostPersons()
+
+	token := csrfToken()
+	request := httptest.NewRequest(http.MethodPost, "/persons", strings.NewReader("[]"))
+	request.AddCookie(&http.Cookie{Name: "csrf_token", Value: token})
+	request.Header.Set("X-CSRF-Token", token)
+	request.Header.Set("Origin", "http://example.com")
+	recorder := httptest.NewRecorder()
+
+	HandleRequest(recorder, request)
+
+	assert.Equal(t, recorder.Code, 200, "Response status code is 200")
+}
+
+func TestCSRFTokenInFormResponds200(t *testing.T) {
+	reset()
+	registerCSRF()
+	registerPostPersons()
+
+	token := csrfToken()
+	request := httptest.NewRequest(http.MethodPost, "/persons", strings.NewReader(url.Values{"csrf_token": {token}}.Encode()))
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.AddCookie(&http.Cookie{Name: "csrf_token", Value: token})
+	recorder := httptest.NewRecorder()
+
+	HandleRequest(recorder, request)
+
+	assert.Equal(t, recorder.Code, 200, "Response status code is 200")
+}
+
+func TestCSRFTokenInFormKeepsBodyForHandler(t *testing.T) {
+	reset()
+	registerCSRF()
+	registerPostRawBody()
+
+	token := csrfToken()
+	form := url.Values{"csrf_token": {token}, "name": {"mario"}}.Encode()
+	request := httptest.NewRequest(http.MethodPost, "/persons", strings.NewReader(form))
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.AddCookie(&http.Cookie{Name: "csrf_token", Value: token})
+	recorder := httptest.NewRecorder()
+
+	HandleRequest(recorder, request)
+
+	assert.Equal(t, recorder.Code, 200, "Response status code is 200")
+	assert.Equal(t, recorder.Body.String(), form, "Handler reads the complete body")
+}
+
+func TestCSRFTokenInMultipartFormResponds200(t *testing.T) {
+	reset()
+	registerCSRF()
+	registerPostRawBody()
+
+	token := csrfToken()
+	body, contentType := multipartForm(map[string]string{"csrf_token": token}, strings.Repeat("x", 1024))
+	request := httptest.NewRequest(http.MethodPost, "/persons", bytes.NewReader(body))
+	request.Header.Set("Content-Type", contentType)
+	request.AddCookie(&http.Cookie{Name: "csrf_token", Value: token})
+	recorder := httptest.NewRecorder()
+
+	HandleRequest(recorder, request)
+
+	assert.Equal(t, recorder.Code, 200, "Response status code is 200")
+	assert.Equal(t, recorder.Body.String(), string(body), "Handler reads the complete body")
+}
+
+func TestCSRFMultipartFormIsLimited(t *testing.T) {
+	reset()
+	SetBodyLimit(BodyLimit{Multipart: 100})
+	registerCSRF()
+	registerPostRawBody()
+
+	token := csrfToken()
+	body, contentType := multipartForm(map[string]string{"csrf_token": token}, strings.Repeat("x", 5<<20))
+	counted := &countingReader{Reader: bytes.NewReader(body)}
+	request := httptest.NewRequest(http.MethodPost, "/persons", counted)
+	request.ContentLength = -1
+	request.Header.Set("Content-Type", contentType)
+	request.AddCookie(&http.Cookie{Name: "csrf_token", Value: token})
+	recorder := httptest.NewRecorder()
+
+	HandleRequest(recorder, request)
+
+	assert.Equal(t, recorder.Code, 413, "Response status code is 413")
+	assert.Equal(t, counted.read < 64<<10, true, "Body is not read beyond the limit")
+}
+
+func TestCSRFTokenInEncodedFormResponds200(t *testing.T) {
+	reset()
+	registerCSRF()
+	registerPostRawBody()
+
+	token := csrfToken()
+	form := url.Values{"csrf_token": {token}}.Encode()
+	request := httptest.NewRequest(http.MethodPost, "/persons", bytes.NewReader(gzipped(form)))
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Content-Encoding", "gzip")
+	request.AddCookie(&http.Cookie{Name: "csrf_token", Value: token})
+	recorder := httptest.NewRecorder()
+
+	HandleRequest(recorder, request)
+
+	assert.Equal(t, recorder.Code, 200, "Response status code is 200")
+	assert.Equal(t, recorder.Body.String(), form, "Handler reads the decoded body")
+}
+
+func TestCSRFTokenOfAnotherClientResponds403(t *testing.T) {
+	reset()
+	principal := &Principal{Name: "mallory", Scheme: "Basic"}
+	Filter("/.*", func(writer http.ResponseWriter, request *http.Request) {
+		scopeOf(request).principal = principal
+	})
+	registerCSRF()
+	registerPostPersons()
+
+	token := csrfCookie(getCSRFForm())
+	principal = &Principal{Name: "mario", Scheme: "Basic"}
+	request := httptest.NewRequest(http.MethodPost, "/persons", strings.NewReader("[]"))
+	request.AddCookie(&http.Cookie{Name: "csrf_token", Value: token})
+	request.Header.Set("X-CSRF-Token", token)
+	recorder := httptest.NewRecorder()
+
+	HandleRequest(recorder, request)
+
+	assert.Equal(t, recorder.Code, 403, "Response status code is 403")
+}
+
+func TestCSRFTokenOfSameClientResponds200(t *testing.T) {
+	reset()
+	Filter("/.*", func(writer http.ResponseWriter, request *http.Request) {
+		scopeOf(request).principal = &Principal{Name: "mario", Scheme: "Basic"}
+	})
+	registerCSRF()
+	registerPostPersons()
+
+	token := csrfCookie(getCSRFForm())
+	request := httptest.NewRequest(http.MethodPost, "/persons", strings.NewReader("[]"))
+	request.AddCookie(&http.Cookie{Name: "csrf_token", Value: token})
+	request.Header.Set("X-CSRF-Token", token)
+	recorder := httptest.NewRecorder()
+
+	HandleRequest(recorder, request)
+
+	assert.Equal(t, recorder.Code, 200, "Response status code is 200")
+}
+
+func TestCSRFUnsignedCookieResponds403(t *testing.T) {
+	reset()
+	registerCSRF()
+	registerPostPersons()
+
+	request := httptest.NewRequest(http.MethodPost, "/persons", strings.NewReader("[]"))
+	request.AddCookie(&http.Cookie{Name: "csrf_token", Value: "forged.token"})
+	request.Header.Set("X-CSRF-Token", "forged.token")
+	recorder := httptest.NewRecorder()
+
+	HandleRequest(recorder, request)
+
+	assert.Equal(t, recorder.Code, 403, "Response status code is 403")
+}
+
+func TestCSRFCrossOriginResponds403(t *testing.T) {
+	reset()
+	registerCSRF()
+	registerPostPersons()
+
+	token := csrfToken()
+	request := httptest.NewRequest(http.MethodPost, "/persons", strings.NewReader("[]"))
+	request.AddCookie(&http.Cookie{Name: "csrf_token", Value: token})
+	request.Header.Set("X-CSRF-Token", token)
+	request.Header.Set("Referer", "https://evil.example/attack")
+	recorder := httptest.NewRecorder()
+
+	HandleRequest(recorder, request)
+
+	assert.Equal(t, recorder.Code, 403, "Response status code is 403")
+}
+
+func TestCSRFExemptRouteResponds200(t *testing.T) {
+	reset()
+	registerCSRF()
+	Post("/webhooks/payment", func(req RequestEntity, resp *ResponseEntity) {
+		resp.Status = 200
+	})
+
+	request := httptest.NewRequest(http.MethodPost, "/webhooks/payment", strings.NewReader("[]"))
+	recorder := httptest.NewRecorder()
+
+	HandleRequest(recorder, request)
+
+	assert.Equal(t, recorder.Code, 200, "Response status code is 200")
+}
+
+var csrfSecret = []byte("csrf secret")
+
+func registerCSRF() {
+	Filter("/.*", NewCSRF(csrfSecret).Exempt("/webhooks/.*").Filter)
+}
+
+func csrfToken() string {
+	return NewCSRF(csrfSecret).newToken(httptest.NewRequest(http.MethodGet, "/", nil))
+}
+
+func getCSRFForm() *httptest.ResponseRecorder {
+	Get("/form", func(req RequestEntity, resp *ResponseEntity) {
+		resp.Status = 200
+	})
+
+	recorder := httptest.NewRecorder()
+	HandleRequest(recorder, httptest.NewRequest(http.MethodGet, "/form", strings.NewReader("")))
+
+	return recorder
+}
+
+func csrfCookie(recorder *httptest.ResponseRecorder) string {
+	for _, cookie := range recorder.Result().Cookies() {
+		if cookie.Name == "csrf_token" {
+			return cookie.Value
+		}
+	}
+
+	return ""
+}
+
+// multipartForm puts the fields before a file part
+func multipartForm(fields map[string]string, file string) ([]byte, string) {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+
+	for name, value := range fields {
+		writer.WriteField(name, value)
+	}
+
+	part, _ := writer.CreateFormFile("upload", "upload.txt")
+	part.Write([]byte(file))
+	writer.Close()
+
+	return body.Bytes(), writer.FormDataContentType()
+}
+
+type countingReader struct {
+	io.Reader
+	read int
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.Reader.Read(p)
+	c.read += n
+	return n, err
+}
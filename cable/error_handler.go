@@ -0,0 +1,152 @@
+package cable
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"go.uber.org/zap"
+)
+
+// HTTPError is returned by request handlers to respond with an error status
+type HTTPError struct {
+	Status int
+	// Code identifies the error for clients, e.g. person_not_found
+	Code    string
+	Message string
+	// Details are sent to the client as extension member of the problem, they must be
+	// encodable in the negotiated media type
+	Details interface{}
+	// Err is the cause, it is logged but not sent to the client
+	Err error
+}
+
+func NewHTTPError(status int, code string, message string) *HTTPError {
+	return &HTTPError{Status: status, Code: code, Message: message}
+}
+
+// WithDetails returns a copy of the error carrying details
+func (e *HTTPError) WithDetails(details interface{}) *HTTPError {
+	copied := *e
+	copied.Details = details
+
+	return &copied
+}
+
+// Wrap returns a copy of the error caused by err
+func (e *HTTPError) Wrap(err error) *HTTPError {
+	copied := *e
+	copied.Err = err
+
+	return &copied
+}
+
+func (e *HTTPError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%d %s: %v", e.Status, e.Message, e.Err)
+	}
+
+	return fmt.Sprintf("%d %s", e.Status, e.Message)
+}
+
+func (e *HTTPError) Unwrap() error {
+	return e.Err
+}
+
+// ErrorHandler responds to errors returned by request handlers
+type ErrorHandler func(requestEntity RequestEntity, response *ResponseEntity, err error)
+
+type errorMapping struct {
+	target error
+	err    *HTTPError
+}
+
+var (
+	defaultErrorMappings = []errorMapping{
+		{ErrBodyTooLarge, NewHTTPError(http.StatusRequestEntityTooLarge, "body_too_large", ErrBodyTooLarge.Error())},
+		{ErrUnsupportedEncoding, NewHTTPError(http.StatusUnsupportedMediaType, "unsupported_encoding", ErrUnsupportedEncoding.Error())},
+		{ErrUnsupportedMediaType, NewHTTPError(http.StatusUnsupportedMediaType, "unsupported_media_type", ErrUnsupportedMediaType.Error())},
+		{ErrNotAcceptable, NewHTTPError(http.StatusNotAcceptable, "not_acceptable", ErrNotAcceptable.Error())}}
+	errorMappings = append([]errorMapping{}, defaultErrorMappings...)
+	errorHandler  = ErrorHandler(DefaultErrorHandler)
+)
+
+// Handler adapts a request handler returning an error, which is passed to the error
+// handler, e.g. cable.Get(pattern, cable.Handler(handler))
+func Handler(handler func(requestEntity RequestEntity, response *ResponseEntity) error) func(requestEntity RequestEntity, response *ResponseEntity) {
+	return func(requestEntity RequestEntity, response *ResponseEntity) {
+		if err := handler(requestEntity, response); err != nil {
+			errorHandler(requestEntity, response, err)
+		}
+	}
+}
+
+// SetErrorHandler replaces DefaultErrorHandler for all routes
+func SetErrorHandler(handler ErrorHandler) {
+	errorHandler = handler
+}
+
+// MapError responds with err to errors matching target according to errors.Is, e.g.
+// cable.MapError(sql.ErrNoRows, cable.NewHTTPError(404, "not_found", "resource not found")).
+// Later mappings take precedence.
+func MapError(target error, err *HTTPError) {
+	errorMappings = append(errorMappings, errorMapping{target: target, err: err})
+}
+
+// DefaultErrorHandler responds with HTTPErrors and mapped errors. Other errors are logged
+// and responded to with 500 Internal Server Error, without revealing the error.
+func DefaultErrorHandler(requestEntity RequestEntity, response *ResponseEntity, err error) {
+	request := requestEntity.Request
+	httpError := resolveHTTPError(err)
+
+	if httpError.Status >= http.StatusInternalServerError {
+		logger.Error("Request handler failed",
+			zap.String("Path", request.URL.Path),
+			zap.String("Method", request.Method),
+			zap.Error(err))
+	} else {
+		logger.Debug("Request handler returned error",
+			zap.String("Path", request.URL.Path),
+			zap.Int("StatusCode", httpError.Status),
+			zap.Error(err))
+	}
+
+	response.SetProblem(httpError.Problem())
+}
+
+// Problem describes the error to clients, with code and details as extension members
+func (e *HTTPError) Problem() *Problem {
+	problem := NewProblem(e.Status, e.Message)
+
+	if len(e.Code) > 0 {
+		problem.With("code", e.Code)
+	}
+
+	if e.Details != nil {
+		problem.With("details", e.Details)
+	}
+
+	return problem
+}
+
+func resolveHTTPError(err error) *HTTPError {
+	var httpError *HTTPError
+
+	if errors.As(err, &httpError) {
+		return httpError
+	}
+
+	var validationErrors ValidationErrors
+
+	if errors.As(err, &validationErrors) {
+		return NewHTTPError(http.StatusUnprocessableEntity, "validation_failed", "request is invalid").WithDetails(validationErrors)
+	}
+
+	for i := len(errorMappings) - 1; i >= 0; i-- {
+		if errors.Is(err, errorMappings[i].target) {
+			return errorMappings[i].err
+		}
+	}
+
+	return NewHTTPError(http.StatusInternalServerError, "", http.StatusText(http.StatusInternalServerError))
+}
@@ -0,0 +1,95 @@
+package cable
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	assert "github.com/stfsy/golang-assert"
+)
+
+var errPersonNotFound = errors.New("person not found")
+
+func TestHandlerHTTPErrorResponds(t *testing.T) {
+	reset()
+	Get("/persons", Handler(func(req RequestEntity, resp *ResponseEntity) error {
+		return NewHTTPError(http.StatusConflict, "person_exists", "person exists").WithDetails([]string{"firstName"})
+	}))
+
+	recorder := getError("application/json")
+
+	assert.Equal(t, recorder.Code, 409, "Response status code is 409")
+	assert.Equal(t, recorder.Body.String(), `{"type":"about:blank","title":"Conflict","status":409,"detail":"person exists","instance":"/persons","code":"person_exists","details":["firstName"]}`, "Response body describes the error")
+}
+
+func TestHandlerMappedErrorResponds(t *testing.T) {
+	reset()
+	MapError(errPersonNotFound, NewHTTPError(http.StatusNotFound, "person_not_found", "person not found"))
+	Get("/persons", Handler(func(req RequestEntity, resp *ResponseEntity) error {
+		return fmt.Errorf("loading person 42: %w", errPersonNotFound)
+	}))
+
+	recorder := getError("application/xml")
+
+	assert.Equal(t, recorder.Code, 404, "Response status code is 404")
+	assert.Equal(t, recorder.Body.String(), `<problem xmlns="urn:ietf:rfc:7807"><type>about:blank</type><title>Not Found</title><status>404</status><detail>person not found</detail><instance>/persons</instance><code>person_not_found</code></problem>`, "Response body is negotiated")
+}
+
+func TestHandlerBuiltInMappingResponds413(t *testing.T) {
+	reset()
+	SetBodyLimit(BodyLimit{JSON: 10})
+	Post("/persons", Handler(func(req RequestEntity, resp *ResponseEntity) error {
+		err := UnmarshalBody(&Person{}, req.Request)
+		return err
+	}))
+
+	request := httptest.NewRequest(http.MethodPost, "/persons", strings.NewReader(jsonBody))
+	request.Header.Set("Content-Type", "application/json")
+	request.ContentLength = -1
+	recorder := httptest.NewRecorder()
+
+	HandleRequest(recorder, request)
+
+	assert.Equal(t, recorder.Code, 413, "Response status code is 413")
+}
+
+func TestHandlerUnknownErrorResponds500(t *testing.T) {
+	reset()
+	Get("/persons", Handler(func(req RequestEntity, resp *ResponseEntity) error {
+		return errors.New("connection refused")
+	}))
+
+	recorder := getError("application/json")
+
+	assert.Equal(t, recorder.Code, 500, "Response status code is 500")
+	assert.Equal(t, recorder.Body.String(), `{"type":"about:blank","title":"Internal Server Error","status":500,"instance":"/persons"}`, "Error is not revealed")
+}
+
+func TestCustomErrorHandler(t *testing.T) {
+	reset()
+	SetErrorHandler(func(req RequestEntity, resp *ResponseEntity, err error) {
+		resp.Status = http.StatusTeapot
+		resp.Body = []byte(err.Error())
+	})
+	Get("/persons", Handler(func(req RequestEntity, resp *ResponseEntity) error {
+		return errPersonNotFound
+	}))
+
+	recorder := getError("application/json")
+
+	assert.Equal(t, recorder.Code, 418, "Response status code is 418")
+	assert.Equal(t, recorder.Body.String(), "person not found", "Custom body is sent")
+}
+
+func getError(accept string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(http.MethodGet, "/persons", strings.NewReader(""))
+	request.Header.Set("Accept", accept)
+	recorder := httptest.NewRecorder()
+
+	HandleRequest(recorder, request)
+
+	return recorder
+}
@@ -0,0 +1,193 @@
+package cable
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"sort"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+const (
+	problemJSONContentType = "application/problem+json"
+	problemXMLContentType  = "application/problem+xml"
+	problemNamespace       = "urn:ietf:rfc:7807"
+)
+
+/*
+* Problem is the body of error responses generated by cable. Handlers may add members
+* to Extensions, e.g. a list of invalid parameters.
+*
+* https://www.rfc-editor.org/rfc/rfc9457
+ */
+type Problem struct {
+	// Type identifies the problem type with a URI, about:blank if the status code is sufficient
+	Type     string
+	Title    string
+	Status   int
+	Detail   string
+	Instance string
+	// Extensions are serialized as additional members of the problem object
+	Extensions map[string]interface{}
+}
+
+// NewProblem creates a problem of type about:blank, titled with the status text
+func NewProblem(status int, detail string) *Problem {
+	problem := &Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail}
+
+	if problem.Detail == problem.Title {
+		problem.Detail = ""
+	}
+
+	return problem
+}
+
+// With returns the problem with an extension member set
+func (p *Problem) With(name string, value interface{}) *Problem {
+	if p.Extensions == nil {
+		p.Extensions = map[string]interface{}{}
+	}
+
+	p.Extensions[name] = value
+
+	return p
+}
+
+func (p Problem) members() []problemMember {
+	members := []problemMember{}
+
+	for _, member := range []problemMember{
+		{"type", p.Type},
+		{"title", p.Title},
+		{"status", p.Status},
+		{"detail", p.Detail},
+		{"instance", p.Instance}} {
+		if member.Value != "" && member.Value != 0 {
+			members = append(members, member)
+		}
+	}
+
+	names := []string{}
+
+	for name := range p.Extensions {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		members = append(members, problemMember{name, p.Extensions[name]})
+	}
+
+	return members
+}
+
+type problemMember struct {
+	Name  string
+	Value interface{}
+}
+
+func (p Problem) MarshalJSON() ([]byte, error) {
+	buffer := strings.Builder{}
+	buffer.WriteString("{")
+
+	for i, member := range p.members() {
+		name, _ := json.Marshal(member.Name)
+		value, err := json.Marshal(member.Value)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if i > 0 {
+			buffer.WriteString(",")
+		}
+
+		buffer.Write(name)
+		buffer.WriteString(":")
+		buffer.Write(value)
+	}
+
+	buffer.WriteString("}")
+
+	return []byte(buffer.String()), nil
+}
+
+// MarshalXML follows https://www.rfc-editor.org/rfc/rfc9457#appendix-B
+func (p Problem) MarshalXML(encoder *xml.Encoder, start xml.StartElement) error {
+	start = xml.StartElement{Name: xml.Name{Space: problemNamespace, Local: "problem"}}
+
+	if err := encoder.EncodeToken(start); err != nil {
+		return err
+	}
+
+	for _, member := range p.members() {
+		if err := encoder.EncodeElement(member.Value, xml.StartElement{Name: xml.Name{Local: member.Name}}); err != nil {
+			return err
+		}
+	}
+
+	return encoder.EncodeToken(start.End())
+}
+
+// writeError responds with status and a problem in a media type the client accepts.
+// If the client accepts none, the response has no body.
+func writeError(writer http.ResponseWriter, request *http.Request, status int, message string) {
+	response := ResponseEntity{Request: request}
+	setError(&response, request, status, message)
+
+	for key, value := range response.Header {
+		writer.Header().Set(key, value)
+	}
+
+	writer.WriteHeader(response.Status)
+	writer.Write(response.Body)
+}
+
+// setError is writeError for interceptors and request handlers
+func setError(response *ResponseEntity, request *http.Request, status int, message string) {
+	response.SetProblem(NewProblem(status, message))
+}
+
+// SetProblem responds with the status of problem and the problem as body. The request
+// path is used as instance if none is set.
+func (r *ResponseEntity) SetProblem(problem *Problem) {
+	request := r.Request
+
+	if len(problem.Instance) == 0 && request != nil {
+		problem.Instance = request.URL.Path
+	}
+
+	r.Status = problem.Status
+	r.Body = nil
+
+	if request == nil {
+		return
+	}
+
+	body, contentType, err := marshalBody(problem, request)
+
+	if err != nil {
+		logger.Debug("Sending error without body",
+			zap.String("Path", request.URL.Path),
+			zap.Int("StatusCode", problem.Status),
+			zap.Error(err))
+		return
+	}
+
+	switch {
+	case contentType == "application/json" || strings.HasSuffix(contentType, "+json"):
+		contentType = problemJSONContentType
+	case contentType == "application/xml" || strings.HasSuffix(contentType, "+xml"):
+		contentType = problemXMLContentType
+	}
+
+	r.SetHeader(contentTypeHeader, contentType)
+	r.Body = body
+}
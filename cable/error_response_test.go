@@ -0,0 +1,82 @@
+package cable
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	assert "github.com/stfsy/golang-assert"
+)
+
+func TestNotFoundRespondsProblem(t *testing.T) {
+	reset()
+
+	recorder := requestProblem(http.MethodGet, "/unknown", "application/json")
+
+	assert.Equal(t, recorder.Code, 404, "Response status code is 404")
+	assert.Equal(t, recorder.Header().Get("Content-Type"), "application/problem+json", "Response body is a problem")
+	assert.Equal(t, recorder.Body.String(), `{"type":"about:blank","title":"Not Found","status":404,"detail":"no handler matches the path","instance":"/unknown"}`, "Response body describes the error")
+}
+
+func TestMethodNotAllowedRespondsProblem(t *testing.T) {
+	reset()
+	registerGetPersons()
+
+	recorder := requestProblem(http.MethodDelete, "/persons", "application/problem+xml")
+
+	assert.Equal(t, recorder.Code, 405, "Response status code is 405")
+	assert.Equal(t, recorder.Header().Get("Allow"), "GET", "Allowed methods are listed")
+	assert.Equal(t, recorder.Header().Get("Content-Type"), "application/problem+xml", "Response body is a problem")
+	assert.Equal(t, recorder.Body.String(), `<problem xmlns="urn:ietf:rfc:7807"><type>about:blank</type><title>Method Not Allowed</title><status>405</status><detail>method is not allowed for the path</detail><instance>/persons</instance></problem>`, "Response body describes the error")
+}
+
+func TestCustomNotFoundHandler(t *testing.T) {
+	reset()
+	NotFound(func(req RequestEntity, resp *ResponseEntity) {
+		resp.Status = http.StatusNotFound
+		resp.Body = []byte("Nothing here")
+	})
+
+	recorder := requestProblem(http.MethodGet, "/unknown", "application/json")
+
+	assert.Equal(t, recorder.Code, 404, "Response status code is 404")
+	assert.Equal(t, recorder.Body.String(), "Nothing here", "Custom body is sent")
+}
+
+func TestCustomMethodNotAllowedHandler(t *testing.T) {
+	reset()
+	registerGetPersons()
+	MethodNotAllowed(func(req RequestEntity, resp *ResponseEntity) {
+		resp.Status = http.StatusNotFound
+	})
+
+	recorder := requestProblem(http.MethodDelete, "/persons", "application/json")
+
+	assert.Equal(t, recorder.Code, 404, "Response status code is 404")
+}
+
+func TestHandlerExtendsProblem(t *testing.T) {
+	reset()
+	Get("/persons", func(req RequestEntity, resp *ResponseEntity) {
+		problem := NewProblem(http.StatusForbidden, "your balance is 30, but the book costs 50")
+		problem.Type = "https://example.com/probs/out-of-credit"
+		problem.Title = "You do not have enough credit."
+		resp.SetProblem(problem.With("balance", 30))
+	})
+
+	recorder := requestProblem(http.MethodGet, "/persons", "application/json")
+
+	assert.Equal(t, recorder.Code, 403, "Response status code is 403")
+	assert.Equal(t, recorder.Body.String(), `{"type":"https://example.com/probs/out-of-credit","title":"You do not have enough credit.","status":403,"detail":"your balance is 30, but the book costs 50","instance":"/persons","balance":30}`, "Response body has extension members")
+}
+
+func requestProblem(method string, path string, accept string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, path, strings.NewReader(""))
+	request.Header.Set("Accept", accept)
+	recorder := httptest.NewRecorder()
+
+	HandleRequest(recorder, request)
+
+	return recorder
+}
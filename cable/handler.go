@@ -1,7 +1,9 @@
 package cable
 
 import (
+	"net"
 	"net/http"
+	"net/textproto"
 	"regexp"
 	"sort"
 	"strings"
@@ -19,7 +21,8 @@ var (
 		http.MethodPost:   []RequestHandler{},
 		http.MethodPut:    []RequestHandler{},
 		http.MethodPatch:  []RequestHandler{}}
-	filters = []mappedFilter{}
+	filters      = []mappedFilter{}
+	interceptors = []mappedInterceptor{}
 )
 
 type filter func(writer http.ResponseWriter, request *http.Request)
@@ -29,10 +32,18 @@ type mappedFilter struct {
 	Handle  filter
 }
 
-//
+type interceptor func(requestEntity RequestEntity, response *ResponseEntity, next func(requestEntity RequestEntity, response *ResponseEntity))
+
+type mappedInterceptor struct {
+	Pattern *regexp.Regexp
+	Handle  interceptor
+}
+
 type RequestHandler struct {
 	Pattern *regexp.Regexp
 	Handle  func(requestEntity RequestEntity, response *ResponseEntity)
+	// pattern as registered, before it was compiled
+	pattern string
 }
 
 type ResponseWriter struct {
@@ -57,11 +68,20 @@ func (r *ResponseWriter) Write(bytes []byte) (int, error) {
 
 type RequestEntity struct {
 	Request *http.Request
+	// Principal is resolved by an authentication filter, nil for anonymous requests
+	Principal *Principal
+	// ClientIP, Scheme and Host as requested by the client, resolved through trusted proxies
+	ClientIP string
+	Scheme   string
+	Host     string
+	// Session is loaded by a sessions interceptor, nil if none is registered
+	Session *Session
 }
 
 type ResponseEntity struct {
 	Body    []byte
 	Header  map[string]string
+	Cookies []*http.Cookie
 	Request *http.Request
 	Status  int
 }
@@ -71,33 +91,48 @@ var (
 	four0FourRequestHandler = RequestHandler{
 		Pattern: wildCardRegex,
 		Handle: func(requestEntity RequestEntity, response *ResponseEntity) {
-			response.Status = http.StatusNotFound
+			notFoundHandler(requestEntity, response)
 		}}
 
 	four0FiveRequestHandler = RequestHandler{
 		Pattern: wildCardRegex,
 		Handle: func(requestEntity RequestEntity, response *ResponseEntity) {
-			response.Status = http.StatusMethodNotAllowed
+			methodNotAllowedHandler(requestEntity, response)
 		}}
 
 	four0SixRequestHandler = RequestHandler{
 		Pattern: wildCardRegex,
 		Handle: func(requestEntity RequestEntity, response *ResponseEntity) {
-			response.Status = http.StatusNotAcceptable
+			setError(response, requestEntity.Request, http.StatusNotAcceptable, "none of the accepted media types can be produced")
 		}}
 
 	four1FiveRequestHandler = RequestHandler{
 		Pattern: wildCardRegex,
 		Handle: func(requestEntity RequestEntity, response *ResponseEntity) {
-			response.Status = http.StatusUnsupportedMediaType
+			setError(response, requestEntity.Request, http.StatusUnsupportedMediaType, "content type is not supported")
 		}}
+
+	notFoundHandler         = defaultNotFoundHandler
+	methodNotAllowedHandler = defaultMethodNotAllowedHandler
 )
 
+func defaultNotFoundHandler(requestEntity RequestEntity, response *ResponseEntity) {
+	setError(response, requestEntity.Request, http.StatusNotFound, "no handler matches the path")
+}
+
+func defaultMethodNotAllowedHandler(requestEntity RequestEntity, response *ResponseEntity) {
+	setError(response, requestEntity.Request, http.StatusMethodNotAllowed, "method is not allowed for the path")
+}
+
 func HandleRequest(writer http.ResponseWriter, request *http.Request) {
+	request = withRequestScope(request)
+	scope := scopeOf(request)
+	resolveClient(request, scope)
 
 	logger.Debug("Handling Request",
 		zap.String("Path", request.URL.Path),
-		zap.String("Method", request.Method))
+		zap.String("Method", request.Method),
+		zap.String("ClientIP", scope.clientIP))
 
 	wrappedWriter := ResponseWriter{writer, false}
 	handler := findHandler(request.URL.Path, request.Method, writer)
@@ -113,9 +148,31 @@ func HandleRequest(writer http.ResponseWriter, request *http.Request) {
 	}
 
 	responseEntity := ResponseEntity{Request: request}
-	requestEntity := RequestEntity{Request: request}
+	requestEntity := RequestEntity{
+		Request:   request,
+		Principal: scope.principal,
+		ClientIP:  scope.clientIP,
+		Scheme:    scope.scheme,
+		Host:      scope.host}
+
+	if !authorize(request, requestEntity.Principal, handler.pattern) {
+		writeError(&wrappedWriter, request, http.StatusForbidden, "access is denied")
+		return
+	}
 
-	handler.Handle(requestEntity, &responseEntity)
+	if !prepareBody(&wrappedWriter, request) {
+		return
+	}
+
+	// interceptors read headers by their canonical key, whatever spelling inner handlers used
+	handle := withCanonicalHeader(handler.Handle)
+	matchingInterceptors := findInterceptors(request.URL.Path)
+
+	for i := len(matchingInterceptors) - 1; i >= 0; i-- {
+		handle = withCanonicalHeader(chainInterceptor(matchingInterceptors[i].Handle, handle))
+	}
+
+	handle(requestEntity, &responseEntity)
 
 	logger.Debug("Handling Response",
 		zap.String("Path", request.URL.Path),
@@ -123,10 +180,51 @@ func HandleRequest(writer http.ResponseWriter, request *http.Request) {
 		zap.Int("StatusCode", responseEntity.Status),
 		zap.String("Response", string(responseEntity.Body)))
 
+	for key, value := range responseEntity.Header {
+		wrappedWriter.Header().Set(key, value)
+	}
+
+	for _, cookie := range responseEntity.Cookies {
+		http.SetCookie(&wrappedWriter, cookie)
+	}
+
 	wrappedWriter.WriteHeader(responseEntity.Status)
 	wrappedWriter.Write(responseEntity.Body)
 }
 
+func withCanonicalHeader(next func(requestEntity RequestEntity, response *ResponseEntity)) func(requestEntity RequestEntity, response *ResponseEntity) {
+	return func(requestEntity RequestEntity, response *ResponseEntity) {
+		next(requestEntity, response)
+		response.canonicalizeHeader()
+	}
+}
+
+// canonicalizeHeader rewrites header keys like ETag or cache-control to the canonical
+// form, e.g. Etag and Cache-Control. If several keys only differ in case, the keys
+// spelled like the canonical form are replaced by the others.
+func (r *ResponseEntity) canonicalizeHeader() {
+	keys := []string{}
+
+	for key := range r.Header {
+		if key != textproto.CanonicalMIMEHeaderKey(key) {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		r.Header[textproto.CanonicalMIMEHeaderKey(key)] = r.Header[key]
+		delete(r.Header, key)
+	}
+}
+
+func chainInterceptor(i interceptor, next func(requestEntity RequestEntity, response *ResponseEntity)) func(requestEntity RequestEntity, response *ResponseEntity) {
+	return func(requestEntity RequestEntity, response *ResponseEntity) {
+		i(requestEntity, response, next)
+	}
+}
+
 func findFilter(path string) []mappedFilter {
 	matchingfilter := []mappedFilter{}
 
@@ -141,6 +239,20 @@ func findFilter(path string) []mappedFilter {
 	return matchingfilter
 }
 
+func findInterceptors(path string) []mappedInterceptor {
+	matchingInterceptors := []mappedInterceptor{}
+
+	for _, r := range interceptors {
+		match := r.Pattern.FindString(path)
+
+		if len(match) > 0 {
+			matchingInterceptors = append(matchingInterceptors, r)
+		}
+	}
+
+	return matchingInterceptors
+}
+
 func findHandler(path string, method string, writer http.ResponseWriter) RequestHandler {
 	matchingHandlers := findHandlersForPathAndMethod(path, method)
 
@@ -195,7 +307,7 @@ func findHandlersForPathAndMethod(path string, method string) sortables.Sorteabl
 
 func registerHandler(method string, pattern string, handler func(requestEntity RequestEntity, response *ResponseEntity)) {
 	compiledPattern := stringToRegex(pattern)
-	handlers[method] = append(handlers[method], RequestHandler{Pattern: compiledPattern, Handle: handler})
+	handlers[method] = append(handlers[method], RequestHandler{Pattern: compiledPattern, Handle: handler, pattern: pattern})
 
 	logger.Info("Registered Handler",
 		zap.String("Pattern", compiledPattern.String()),
@@ -237,6 +349,14 @@ func registerFilter(pattern string, handler filter) {
 		zap.String("Pattern", pattern))
 }
 
+func registerInterceptor(pattern string, handler interceptor) {
+	compiledPattern := stringToRegex(pattern)
+	interceptors = append(interceptors, mappedInterceptor{Pattern: compiledPattern, Handle: handler})
+
+	logger.Info("Registered Interceptor",
+		zap.String("Pattern", pattern))
+}
+
 func reset() {
 	handlers = map[string][]RequestHandler{
 		http.MethodDelete: []RequestHandler{},
@@ -245,4 +365,15 @@ func reset() {
 		http.MethodPut:    []RequestHandler{},
 		http.MethodPatch:  []RequestHandler{}}
 	filters = []mappedFilter{}
+	interceptors = []mappedInterceptor{}
+	authorizations = []mappedAuthorization{}
+	bodyLimit = defaultBodyLimit
+	contentDecoders = copyContentDecoders(defaultContentDecoders)
+	errorMappings = append([]errorMapping{}, defaultErrorMappings...)
+	errorHandler = DefaultErrorHandler
+	notFoundHandler = defaultNotFoundHandler
+	methodNotAllowedHandler = defaultMethodNotAllowedHandler
+	validators = copyValidators(defaultValidators)
+	clearStructRules()
+	trustedProxies = []*net.IPNet{}
 }
@@ -21,3 +21,14 @@ func Put(pattern string, handler func(requestEntity RequestEntity, response *Res
 func Patch(pattern string, handler func(requestEntity RequestEntity, response *ResponseEntity)) {
 	registerHandler(http.MethodPatch, pattern, handler)
 }
+
+// NotFound replaces the handler of requests no route matches
+func NotFound(handler func(requestEntity RequestEntity, response *ResponseEntity)) {
+	notFoundHandler = handler
+}
+
+// MethodNotAllowed replaces the handler of requests only routes of other methods match.
+// The Allow header is set before the handler is called.
+func MethodNotAllowed(handler func(requestEntity RequestEntity, response *ResponseEntity)) {
+	methodNotAllowedHandler = handler
+}
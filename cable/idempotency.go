@@ -0,0 +1,250 @@
+package cable
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	idempotencyKeyHeader     = "Idempotency-Key"
+	idempotentReplayedHeader = "Idempotent-Replayed"
+	defaultIdempotencyExpiry = 24 * time.Hour
+)
+
+// IdempotencyRecord is the state of a request with an idempotency key. Status, Header
+// and Body are set once the first request completed.
+type IdempotencyRecord struct {
+	// Fingerprint identifies the payload the key was first used with
+	Fingerprint string
+	Completed   bool
+	Status      int
+	Header      map[string]string
+	Body        []byte
+	Expires     time.Time
+}
+
+// IdempotencyStore keeps records per key. Implementations must be safe for concurrent use.
+type IdempotencyStore interface {
+	// Reserve stores record unless an unexpired record exists for key, which is
+	// returned instead together with false
+	Reserve(key string, record IdempotencyRecord, now time.Time) (IdempotencyRecord, bool, error)
+	Complete(key string, record IdempotencyRecord) error
+	// Release forgets key, so the request can be retried
+	Release(key string) error
+}
+
+// Idempotency replays the first response to POST and PATCH requests retried with the
+// same Idempotency-Key header.
+//
+// https://datatracker.ietf.org/doc/draft-ietf-httpapi-idempotency-key-header/
+type Idempotency struct {
+	Store IdempotencyStore
+	// Expiry is the time a key is remembered for
+	Expiry time.Duration
+	clock  func() time.Time
+}
+
+// NewIdempotency keeps records in memory for 24 hours. Register it for routes opting in
+// with cable.Intercept(pattern, idempotency.Intercept).
+func NewIdempotency() *Idempotency {
+	return &Idempotency{
+		Store:  NewMemoryIdempotencyStore(),
+		Expiry: defaultIdempotencyExpiry,
+		clock:  time.Now}
+}
+
+func (i *Idempotency) Intercept(requestEntity RequestEntity, response *ResponseEntity, next func(requestEntity RequestEntity, response *ResponseEntity)) {
+	request := requestEntity.Request
+	key := request.Header.Get(idempotencyKeyHeader)
+
+	if len(key) == 0 || (request.Method != http.MethodPost && request.Method != http.MethodPatch) {
+		next(requestEntity, response)
+		return
+	}
+
+	body, err := ioutil.ReadAll(request.Body)
+
+	if err != nil {
+		var maxBytesError *http.MaxBytesError
+
+		if errors.As(err, &maxBytesError) {
+			setError(response, request, http.StatusRequestEntityTooLarge, ErrBodyTooLarge.Error())
+		} else {
+			setError(response, request, http.StatusBadRequest, "request body could not be read")
+		}
+
+		return
+	}
+
+	request.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	// keys are scoped to the principal, so clients cannot replay each others responses
+	key = idempotencyScope(requestEntity.Principal, key)
+
+	now := i.clock()
+	fingerprint := idempotencyFingerprint(request, body)
+	existing, reserved, err := i.Store.Reserve(key, IdempotencyRecord{Fingerprint: fingerprint, Expires: now.Add(i.Expiry)}, now)
+
+	if err != nil {
+		logger.Error("Idempotency store failed", zap.Error(err))
+		setError(response, request, http.StatusServiceUnavailable, http.StatusText(http.StatusServiceUnavailable))
+		return
+	}
+
+	if !reserved {
+		i.replay(existing, fingerprint, request, response)
+		return
+	}
+
+	completed := false
+
+	defer func() {
+		if !completed {
+			i.Store.Release(key)
+		}
+	}()
+
+	next(requestEntity, response)
+
+	// server errors are not remembered, the client may retry them
+	if response.Status >= http.StatusInternalServerError {
+		return
+	}
+
+	stored := copyResponseEntity(*response)
+
+	if err := i.Store.Complete(key, IdempotencyRecord{
+		Fingerprint: fingerprint,
+		Completed:   true,
+		Status:      stored.Status,
+		Header:      stored.Header,
+		Body:        stored.Body,
+		Expires:     now.Add(i.Expiry)}); err != nil {
+		logger.Error("Idempotency store failed", zap.Error(err))
+		return
+	}
+
+	completed = true
+}
+
+func (i *Idempotency) replay(existing IdempotencyRecord, fingerprint string, request *http.Request, response *ResponseEntity) {
+	if existing.Fingerprint != fingerprint {
+		logger.Info("Idempotency key reused",
+			zap.String("Path", request.URL.Path))
+
+		setError(response, request, http.StatusUnprocessableEntity, "idempotency key was used for a different request")
+		return
+	}
+
+	if !existing.Completed {
+		setError(response, request, http.StatusConflict, "request with idempotency key is in progress")
+		return
+	}
+
+	logger.Debug("Replaying response",
+		zap.String("Path", request.URL.Path),
+		zap.Int("StatusCode", existing.Status))
+
+	replayed := copyResponseEntity(ResponseEntity{Request: request, Status: existing.Status, Header: existing.Header, Body: existing.Body})
+	replayed.Header[idempotentReplayedHeader] = "true"
+	*response = replayed
+}
+
+// idempotencyScope hashes the key together with scheme and name of the principal.
+// Every part is length prefixed, so no key of one principal equals a key of another
+// or of anonymous clients.
+func idempotencyScope(principal *Principal, key string) string {
+	parts := []string{"anonymous", key}
+
+	if principal != nil {
+		parts = []string{"principal", principal.Scheme, principal.Name, key}
+	}
+
+	hash := sha256.New()
+
+	for _, part := range parts {
+		fmt.Fprintf(hash, "%d:%s", len(part), part)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func idempotencyFingerprint(request *http.Request, body []byte) string {
+	hash := sha256.New()
+
+	// the query and media type change the meaning of a request as much as its body
+	for _, part := range []string{request.Method, request.URL.Path, request.URL.RawQuery, request.Header.Get(contentTypeHeader)} {
+		fmt.Fprintf(hash, "%d:%s", len(part), part)
+	}
+
+	hash.Write(body)
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+type memoryIdempotencyStore struct {
+	mutex     sync.Mutex
+	records   map[string]IdempotencyRecord
+	lastSweep time.Time
+}
+
+// NewMemoryIdempotencyStore keeps records of a single instance
+func NewMemoryIdempotencyStore() IdempotencyStore {
+	return &memoryIdempotencyStore{records: map[string]IdempotencyRecord{}}
+}
+
+func (s *memoryIdempotencyStore) Reserve(key string, record IdempotencyRecord, now time.Time) (IdempotencyRecord, bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.sweep(now)
+
+	if existing, ok := s.records[key]; ok && now.Before(existing.Expires) {
+		return existing, false, nil
+	}
+
+	s.records[key] = record
+
+	return record, true, nil
+}
+
+func (s *memoryIdempotencyStore) Complete(key string, record IdempotencyRecord) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.records[key] = record
+
+	return nil
+}
+
+func (s *memoryIdempotencyStore) Release(key string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.records, key)
+
+	return nil
+}
+
+func (s *memoryIdempotencyStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < time.Minute {
+		return
+	}
+
+	for key, record := range s.records {
+		if !now.Before(record.Expires) {
+			delete(s.records, key)
+		}
+	}
+
+	s.lastSweep = now
+}
@@ -0,0 +1,177 @@
+package cable
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	assert "github.com/stfsy/golang-assert"
+)
+
+func TestIdempotencyReplaysResponse(t *testing.T) {
+	reset()
+	created := registerIdempotentPost()
+
+	first := postIdempotent("key-1", jsonBody)
+	second := postIdempotent("key-1", jsonBody)
+
+	assert.Equal(t, first.Code, 201, "Response status code is 201")
+	assert.Equal(t, second.Code, 201, "Response status code is replayed")
+	assert.Equal(t, second.Body.String(), "person 1", "Response body is replayed")
+	assert.Equal(t, second.Header().Get("Location"), "/persons/1", "Response header is replayed")
+	assert.Equal(t, second.Header().Get("Idempotent-Replayed"), "true", "Replay is marked")
+	assert.Equal(t, atomic.LoadInt32(created), int32(1), "Handler is called once")
+}
+
+func TestIdempotencyDifferentKeysAreHandled(t *testing.T) {
+	reset()
+	created := registerIdempotentPost()
+
+	postIdempotent("key-1", jsonBody)
+	postIdempotent("key-2", jsonBody)
+	postIdempotent("", jsonBody)
+
+	assert.Equal(t, atomic.LoadInt32(created), int32(3), "Handler is called for each request")
+}
+
+func TestIdempotencyKeyReuseResponds422(t *testing.T) {
+	reset()
+	registerIdempotentPost()
+
+	postIdempotent("key-1", jsonBody)
+	recorder := postIdempotent("key-1", `{"firstName":"Luigi"}`)
+
+	assert.Equal(t, recorder.Code, 422, "Response status code is 422")
+}
+
+func TestIdempotencyKeyReuseWithOtherQueryResponds422(t *testing.T) {
+	reset()
+	registerIdempotentPost()
+
+	first := postIdempotentTo("/persons?amount=1", "application/json", "key-1", jsonBody)
+	second := postIdempotentTo("/persons?amount=1000", "application/json", "key-1", jsonBody)
+
+	assert.Equal(t, first.Code, 201, "Response status code is 201")
+	assert.Equal(t, second.Code, 422, "Response status code is 422")
+}
+
+func TestIdempotencyKeyReuseWithOtherContentTypeResponds422(t *testing.T) {
+	reset()
+	registerIdempotentPost()
+
+	postIdempotentTo("/persons", "application/json", "key-1", jsonBody)
+	recorder := postIdempotentTo("/persons", "text/plain", "key-1", jsonBody)
+
+	assert.Equal(t, recorder.Code, 422, "Response status code is 422")
+}
+
+func TestIdempotencyConcurrentDuplicateResponds409(t *testing.T) {
+	reset()
+	started := make(chan struct{})
+	release := make(chan struct{})
+	Intercept("/persons", NewIdempotency().Intercept)
+	Post("/persons", func(req RequestEntity, resp *ResponseEntity) {
+		close(started)
+		<-release
+		resp.Status = 201
+	})
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		done <- postIdempotent("key-1", jsonBody)
+	}()
+
+	<-started
+	duplicate := postIdempotent("key-1", jsonBody)
+	close(release)
+
+	assert.Equal(t, duplicate.Code, 409, "Response status code is 409")
+	assert.Equal(t, (<-done).Code, 201, "First request completes")
+}
+
+func TestIdempotencyServerErrorIsNotRemembered(t *testing.T) {
+	reset()
+	calls := 0
+	Intercept("/persons", NewIdempotency().Intercept)
+	Post("/persons", func(req RequestEntity, resp *ResponseEntity) {
+		calls++
+		resp.Status = 201
+
+		if calls == 1 {
+			resp.Status = 500
+		}
+	})
+
+	first := postIdempotent("key-1", jsonBody)
+	second := postIdempotent("key-1", jsonBody)
+
+	assert.Equal(t, first.Code, 500, "Response status code is 500")
+	assert.Equal(t, second.Code, 201, "Retry is handled")
+}
+
+func TestIdempotencyKeysAreScopedToPrincipal(t *testing.T) {
+	reset()
+	principal := &Principal{Name: "mario"}
+	Filter("/.*", func(writer http.ResponseWriter, request *http.Request) {
+		scopeOf(request).principal = principal
+	})
+	created := registerIdempotentPost()
+
+	postIdempotent("key-1", jsonBody)
+	principal = &Principal{Name: "luigi"}
+	postIdempotent("key-1", jsonBody)
+
+	assert.Equal(t, atomic.LoadInt32(created), int32(2), "Handler is called for each principal")
+}
+
+func TestIdempotencyKeysAreScopedUnambiguously(t *testing.T) {
+	reset()
+	var principal *Principal
+	Filter("/.*", func(writer http.ResponseWriter, request *http.Request) {
+		scopeOf(request).principal = principal
+	})
+	created := registerIdempotentPost()
+
+	postIdempotent("mario:abc", jsonBody)
+	principal = &Principal{Name: "mario", Scheme: "Basic"}
+	postIdempotent("abc", jsonBody)
+	principal = &Principal{Name: "mario", Scheme: "Bearer"}
+	postIdempotent("abc", jsonBody)
+
+	assert.Equal(t, atomic.LoadInt32(created), int32(3), "Handler is called for anonymous clients and each scheme")
+}
+
+func registerIdempotentPost() *int32 {
+	created := new(int32)
+	Intercept("/persons", NewIdempotency().Intercept)
+	Post("/persons", func(req RequestEntity, resp *ResponseEntity) {
+		id := strconv.Itoa(int(atomic.AddInt32(created, 1)))
+		resp.Status = 201
+		resp.Header = map[string]string{"Location": "/persons/" + id}
+		resp.Body = []byte("person " + id)
+	})
+
+	return created
+}
+
+func postIdempotent(key string, body string) *httptest.ResponseRecorder {
+	return postIdempotentTo("/persons", "application/json", key, body)
+}
+
+func postIdempotentTo(target string, contentType string, key string, body string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+	request.Header.Set("Content-Type", contentType)
+
+	if len(key) > 0 {
+		request.Header.Set("Idempotency-Key", key)
+	}
+
+	recorder := httptest.NewRecorder()
+
+	HandleRequest(recorder, request)
+
+	return recorder
+}
@@ -0,0 +1,7 @@
+package cable
+
+// Intercept wraps the request handlers of all routes matching pattern. Interceptors are
+// called in the order they were registered and continue with the request handler by calling next.
+func Intercept(pattern string, handler func(requestEntity RequestEntity, response *ResponseEntity, next func(requestEntity RequestEntity, response *ResponseEntity))) {
+	registerInterceptor(pattern, handler)
+}
@@ -0,0 +1,49 @@
+package cable
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	assert "github.com/stfsy/golang-assert"
+)
+
+func TestInterceptorsAreCalledInOrder(t *testing.T) {
+	reset()
+	registerPostPersons()
+	registerAppendingInterceptor("/.*", "1")
+	registerAppendingInterceptor("/persons", "2")
+	registerAppendingInterceptor("/bikes", "3")
+
+	request := httptest.NewRequest(http.MethodPost, "/persons", strings.NewReader("[]"))
+	recorder := httptest.NewRecorder()
+
+	HandleRequest(recorder, request)
+
+	assert.Equal(t, recorder.Code, 200, "Response status code is 200")
+	assert.Equal(t, recorder.Body.String(), "Some Persons21", "Inner interceptor is called last")
+}
+
+func TestInterceptorRespondsWithoutHandler(t *testing.T) {
+	reset()
+	registerPostPersons()
+	Intercept("/persons", func(req RequestEntity, resp *ResponseEntity, next func(req RequestEntity, resp *ResponseEntity)) {
+		resp.Status = http.StatusTeapot
+	})
+
+	request := httptest.NewRequest(http.MethodPost, "/persons", strings.NewReader("[]"))
+	recorder := httptest.NewRecorder()
+
+	HandleRequest(recorder, request)
+
+	assert.Equal(t, recorder.Code, 418, "Response status code is 418")
+	assert.Equal(t, recorder.Body.String(), "", "Handler is not called")
+}
+
+func registerAppendingInterceptor(pattern string, suffix string) {
+	Intercept(pattern, func(req RequestEntity, resp *ResponseEntity, next func(req RequestEntity, resp *ResponseEntity)) {
+		next(req, resp)
+		resp.Body = append(resp.Body, []byte(suffix)...)
+	})
+}
@@ -0,0 +1,187 @@
+package cable
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// IPFilter restricts routes to clients by their ip, resolved through trusted proxies.
+// Denied networks take precedence. If any network is allowed, all others are denied.
+type IPFilter struct {
+	mutex sync.RWMutex
+	allow []*net.IPNet
+	deny  []*net.IPNet
+	path  string
+}
+
+// NewIPFilter accepts networks in CIDR notation and single addresses. Register it with
+// cable.Filter(pattern, ipFilter.Filter).
+func NewIPFilter(allow []string, deny []string) (*IPFilter, error) {
+	f := &IPFilter{}
+
+	if err := f.Set(allow, deny); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+/*
+* LoadIPFilter reads the networks from a file with one rule per line, e.g.
+*
+*   # office
+*   allow 192.0.2.0/24
+*   allow 2001:db8::/32
+*   deny 192.0.2.13
+ */
+func LoadIPFilter(path string) (*IPFilter, error) {
+	f := &IPFilter{path: path}
+
+	if err := f.Reload(); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// Set replaces the allowed and denied networks
+func (f *IPFilter) Set(allow []string, deny []string) error {
+	allowed, err := parseNetworks(allow)
+
+	if err != nil {
+		return err
+	}
+
+	denied, err := parseNetworks(deny)
+
+	if err != nil {
+		return err
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.allow = allowed
+	f.deny = denied
+
+	return nil
+}
+
+// Reload reads the file again. The current rules are kept if the file is invalid.
+func (f *IPFilter) Reload() error {
+	content, err := ioutil.ReadFile(f.path)
+
+	if err != nil {
+		return err
+	}
+
+	allow, deny := []string{}, []string{}
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		if len(fields) != 2 {
+			return fmt.Errorf("%v:%d: expected rule and network", f.path, line)
+		}
+
+		switch fields[0] {
+		case "allow":
+			allow = append(allow, fields[1])
+		case "deny":
+			deny = append(deny, fields[1])
+		default:
+			return fmt.Errorf("%v:%d: unknown rule %v", f.path, line, fields[0])
+		}
+	}
+
+	if err := f.Set(allow, deny); err != nil {
+		return fmt.Errorf("%v: %v", f.path, err)
+	}
+
+	logger.Info("Loaded IP Filter",
+		zap.String("Path", f.path),
+		zap.Int("Allow", len(allow)),
+		zap.Int("Deny", len(deny)))
+
+	return nil
+}
+
+// Watch reloads the file whenever it was modified, checking every interval until stop is called
+func (f *IPFilter) Watch(interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	ticker := time.NewTicker(interval)
+	modified := modTime(f.path)
+
+	go func() {
+		for {
+			select {
+			case <-done:
+				ticker.Stop()
+				return
+			case <-ticker.C:
+				if m := modTime(f.path); !m.Equal(modified) {
+					modified = m
+
+					if err := f.Reload(); err != nil {
+						logger.Error("Reloading IP Filter failed", zap.Error(err))
+					}
+				}
+			}
+		}
+	}()
+
+	return func() { close(done) }
+}
+
+func (f *IPFilter) Allows(ip net.IP) bool {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+
+	if ip == nil || containsIP(f.deny, ip) {
+		return false
+	}
+
+	return len(f.allow) == 0 || containsIP(f.allow, ip)
+}
+
+func (f *IPFilter) Filter(writer http.ResponseWriter, request *http.Request) {
+	clientIP := ClientIP(request)
+
+	if f.Allows(net.ParseIP(clientIP)) {
+		logger.Debug("IP allowed",
+			zap.String("Path", request.URL.Path),
+			zap.String("ClientIP", clientIP))
+		return
+	}
+
+	logger.Info("IP denied",
+		zap.String("Path", request.URL.Path),
+		zap.String("ClientIP", clientIP))
+
+	writeError(writer, request, http.StatusForbidden, "client ip is not allowed")
+}
+
+func modTime(path string) time.Time {
+	info, err := os.Stat(path)
+
+	if err != nil {
+		return time.Time{}
+	}
+
+	return info.ModTime()
+}
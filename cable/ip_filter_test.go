@@ -0,0 +1,78 @@
+package cable
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	assert "github.com/stfsy/golang-assert"
+)
+
+func TestIPFilterAllowsListedNetworks(t *testing.T) {
+	reset()
+	ipFilter, _ := NewIPFilter([]string{"192.0.2.0/24", "2001:db8::/32"}, nil)
+	Filter("/admin/.*", ipFilter.Filter)
+	registerGetAdminUsers()
+
+	ipv4 := getForwarded("/admin/users", "192.0.2.10:1234", nil)
+	ipv6 := getForwarded("/admin/users", "[2001:db8::10]:1234", nil)
+	other := getForwarded("/admin/users", "198.51.100.1:1234", nil)
+
+	assert.Equal(t, ipv4.Code, 200, "IPv4 in allowed network responds 200")
+	assert.Equal(t, ipv6.Code, 200, "IPv6 in allowed network responds 200")
+	assert.Equal(t, other.Code, 403, "Other networks respond 403")
+	assert.Equal(t, other.Body.String(), `{"type":"about:blank","title":"Forbidden","status":403,"detail":"client ip is not allowed","instance":"/admin/users"}`, "Response body describes the error")
+}
+
+func TestIPFilterDenyTakesPrecedence(t *testing.T) {
+	reset()
+	ipFilter, _ := NewIPFilter([]string{"192.0.2.0/24"}, []string{"192.0.2.13"})
+	Filter("/admin/.*", ipFilter.Filter)
+	registerGetAdminUsers()
+
+	recorder := getForwarded("/admin/users", "192.0.2.13:1234", nil)
+
+	assert.Equal(t, recorder.Code, 403, "Response status code is 403")
+}
+
+func TestIPFilterUsesResolvedClientIP(t *testing.T) {
+	reset()
+	TrustProxies("10.0.0.0/8")
+	ipFilter, _ := NewIPFilter(nil, []string{"203.0.113.0/24"})
+	Filter("/admin/.*", ipFilter.Filter)
+	registerGetAdminUsers()
+
+	recorder := getForwarded("/admin/users", "10.0.0.1:1234", map[string]string{"X-Forwarded-For": "203.0.113.5"})
+
+	assert.Equal(t, recorder.Code, 403, "Response status code is 403")
+}
+
+func TestIPFilterReloadsFile(t *testing.T) {
+	reset()
+	path := filepath.Join(t.TempDir(), "admin.ips")
+	ioutil.WriteFile(path, []byte("# office\nallow 192.0.2.0/24\n"), 0600)
+	ipFilter, err := LoadIPFilter(path)
+	Filter("/admin/.*", ipFilter.Filter)
+	registerGetAdminUsers()
+
+	assert.Equal(t, err, nil, "Error should be nil")
+	assert.Equal(t, getForwarded("/admin/users", "198.51.100.1:1234", nil).Code, 403, "Response status code is 403")
+
+	ioutil.WriteFile(path, []byte("allow 198.51.100.0/24\n"), 0600)
+	err = ipFilter.Reload()
+
+	assert.Equal(t, err, nil, "Error should be nil")
+	assert.Equal(t, getForwarded("/admin/users", "198.51.100.1:1234", nil).Code, 200, "Response status code is 200")
+}
+
+func TestIPFilterKeepsRulesOfInvalidFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "admin.ips")
+	ioutil.WriteFile(path, []byte("allow 192.0.2.0/24\n"), 0600)
+	ipFilter, _ := LoadIPFilter(path)
+
+	ioutil.WriteFile(path, []byte("allow 192.0.2.0/33\n"), 0600)
+	err := ipFilter.Reload()
+
+	assert.NotEqual(t, err, nil, "Error should not be nil")
+	assert.Equal(t, ipFilter.Allows([]byte{192, 0, 2, 1}), true, "Previous rules are kept")
+}
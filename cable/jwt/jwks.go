@@ -0,0 +1,255 @@
+package jwt
+
+import (
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Key verifies tokens signed with Algorithm. If Algorithm is empty the key may be used
+// with any algorithm matching its type.
+type Key struct {
+	ID        string
+	Algorithm string
+	// Key is a []byte for HS256, *rsa.PublicKey, *ecdsa.PublicKey or ed25519.PublicKey
+	Key interface{}
+}
+
+type KeySet interface {
+	// Keys returns the keys with the given id, or all keys if id is empty
+	Keys(id string) ([]Key, error)
+}
+
+// StaticKeySet never changes, e.g. a shared HS256 secret
+type StaticKeySet []Key
+
+func (s StaticKeySet) Keys(id string) ([]Key, error) {
+	if len(id) == 0 {
+		return s, nil
+	}
+
+	keys := []Key{}
+
+	for _, key := range s {
+		if key.ID == id {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys, nil
+}
+
+type jsonWebKey struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Algorithm string `json:"alg"`
+	Use       string `json:"use"`
+	Curve     string `json:"crv"`
+	K         string `json:"k"`
+	N         string `json:"n"`
+	E         string `json:"e"`
+	X         string `json:"x"`
+	Y         string `json:"y"`
+}
+
+type jsonWebKeySet struct {
+	Keys []jsonWebKey `json:"keys"`
+}
+
+/*
+* ParseKeySet reads a JWK Set document. Keys not meant for signatures and key types
+* that are not supported are skipped.
+*
+* https://tools.ietf.org/html/rfc7517#section-5
+ */
+func ParseKeySet(document []byte) (StaticKeySet, error) {
+	set := jsonWebKeySet{}
+
+	if err := json.Unmarshal(document, &set); err != nil {
+		return nil, err
+	}
+
+	keys := StaticKeySet{}
+
+	for _, jwk := range set.Keys {
+		if len(jwk.Use) > 0 && jwk.Use != "sig" {
+			continue
+		}
+
+		key, err := jwk.publicKey()
+
+		if err != nil {
+			return nil, fmt.Errorf("jwt: key %v: %v", jwk.KeyID, err)
+		}
+
+		if key != nil {
+			keys = append(keys, Key{ID: jwk.KeyID, Algorithm: jwk.Algorithm, Key: key})
+		}
+	}
+
+	return keys, nil
+}
+
+func (j jsonWebKey) publicKey() (interface{}, error) {
+	switch j.KeyType {
+	case "oct":
+		return decodeParameter(j.K)
+	case "RSA":
+		n, err := decodeParameter(j.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeParameter(j.E)
+		if err != nil {
+			return nil, err
+		}
+		exponent := new(big.Int).SetBytes(e)
+		if !exponent.IsInt64() || exponent.Int64() > 1<<31-1 {
+			return nil, fmt.Errorf("invalid exponent")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+	case "EC":
+		if j.Curve != "P-256" {
+			return nil, nil
+		}
+		x, err := decodeParameter(j.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeParameter(j.Y)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != 32 || len(y) != 32 {
+			return nil, fmt.Errorf("invalid coordinates")
+		}
+		// let crypto/ecdh reject points that are not on the curve
+		if _, err := ecdh.P256().NewPublicKey(append(append([]byte{4}, x...), y...)); err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	case "OKP":
+		if j.Curve != "Ed25519" {
+			return nil, nil
+		}
+		x, err := decodeParameter(j.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid public key size")
+		}
+		return ed25519.PublicKey(x), nil
+	}
+
+	return nil, nil
+}
+
+func decodeParameter(value string) ([]byte, error) {
+	if len(value) == 0 {
+		return nil, fmt.Errorf("missing key parameter")
+	}
+
+	return base64.RawURLEncoding.DecodeString(value)
+}
+
+// CachedKeySet reloads a JWK Set after TTL, or earlier if a token refers to an
+// unknown key id, so keys can be rotated without a restart
+type CachedKeySet struct {
+	TTL time.Duration
+	// MinRefreshInterval limits reloads triggered by unknown key ids and retries of failed loads
+	MinRefreshInterval time.Duration
+	load               func() ([]byte, error)
+	mutex              sync.Mutex
+	keys               StaticKeySet
+	loaded             time.Time
+	err                error
+	clock              func() time.Time
+}
+
+// NewFileKeySet loads a JWK Set from a local file
+func NewFileKeySet(path string, ttl time.Duration) *CachedKeySet {
+	return newCachedKeySet(ttl, func() ([]byte, error) {
+		return ioutil.ReadFile(path)
+	})
+}
+
+// NewRemoteKeySet fetches a JWK Set from a url, e.g. the jwks_uri of an authorization server
+func NewRemoteKeySet(url string, ttl time.Duration, client *http.Client) *CachedKeySet {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	return newCachedKeySet(ttl, func() ([]byte, error) {
+		response, err := client.Get(url)
+
+		if err != nil {
+			return nil, err
+		}
+
+		defer response.Body.Close()
+
+		if response.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("jwt: fetching %v responded %v", url, response.Status)
+		}
+
+		return ioutil.ReadAll(response.Body)
+	})
+}
+
+func newCachedKeySet(ttl time.Duration, load func() ([]byte, error)) *CachedKeySet {
+	return &CachedKeySet{TTL: ttl, MinRefreshInterval: time.Minute, load: load, clock: time.Now}
+}
+
+func (c *CachedKeySet) Keys(id string) ([]Key, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	now := c.clock()
+	sinceLoad := now.Sub(c.loaded)
+
+	// without any keys a failed load is retried sooner than TTL, but not on every token
+	if c.loaded.IsZero() || sinceLoad >= c.TTL || (c.keys == nil && sinceLoad >= c.MinRefreshInterval) {
+		c.refresh(now)
+	}
+
+	keys, _ := c.keys.Keys(id)
+
+	if len(keys) == 0 && len(id) > 0 && now.Sub(c.loaded) >= c.MinRefreshInterval {
+		c.refresh(now)
+		keys, _ = c.keys.Keys(id)
+	}
+
+	// keep verifying with the cached keys while the key set cannot be reloaded
+	if len(keys) == 0 && c.err != nil {
+		return nil, c.err
+	}
+
+	return keys, nil
+}
+
+func (c *CachedKeySet) refresh(now time.Time) {
+	document, err := c.load()
+
+	if err == nil {
+		var keys StaticKeySet
+		keys, err = ParseKeySet(document)
+
+		if err == nil {
+			c.keys = keys
+		}
+	}
+
+	c.loaded = now
+	c.err = err
+}
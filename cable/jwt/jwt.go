@@ -0,0 +1,324 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+const (
+	HS256 = "HS256"
+	RS256 = "RS256"
+	ES256 = "ES256"
+	EdDSA = "EdDSA"
+)
+
+var (
+	ErrMalformed   = errors.New("jwt: malformed token")
+	ErrAlgorithm   = errors.New("jwt: algorithm not allowed")
+	ErrUnknownKey  = errors.New("jwt: no key to verify token")
+	ErrSignature   = errors.New("jwt: invalid signature")
+	ErrExpired     = errors.New("jwt: token is expired")
+	ErrNotYetValid = errors.New("jwt: token is not valid yet")
+	ErrIssuer      = errors.New("jwt: unexpected issuer")
+	ErrAudience    = errors.New("jwt: unexpected audience")
+
+	allAlgorithms = []string{HS256, RS256, ES256, EdDSA}
+)
+
+// Claims of a verified token
+type Claims map[string]interface{}
+
+func (c Claims) Subject() string {
+	s, _ := c["sub"].(string)
+	return s
+}
+
+func (c Claims) Issuer() string {
+	s, _ := c["iss"].(string)
+	return s
+}
+
+// Audience returns the aud claim, which may be a single string or an array of strings
+func (c Claims) Audience() []string {
+	return c.Strings("aud")
+}
+
+// Strings returns a claim that is either a string or an array of strings, e.g. roles
+func (c Claims) Strings(name string) []string {
+	switch value := c[name].(type) {
+	case string:
+		return strings.Fields(value)
+	case []interface{}:
+		values := []string{}
+		for _, v := range value {
+			if s, ok := v.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+
+	return nil
+}
+
+func (c Claims) time(name string) (time.Time, bool, error) {
+	value, ok := c[name]
+
+	if !ok {
+		return time.Time{}, false, nil
+	}
+
+	number, ok := value.(json.Number)
+
+	if !ok {
+		return time.Time{}, false, ErrMalformed
+	}
+
+	seconds, err := number.Float64()
+
+	if err != nil {
+		return time.Time{}, false, ErrMalformed
+	}
+
+	return time.Unix(0, int64(seconds*float64(time.Second))), true, nil
+}
+
+type header struct {
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+}
+
+type Validator struct {
+	Keys KeySet
+	// Algorithms accepted in the token header, all supported algorithms if empty
+	Algorithms []string
+	// Issuer and Audience are checked if not empty
+	Issuer   string
+	Audience string
+	// ClockSkew is tolerated when checking exp and nbf
+	ClockSkew time.Duration
+	clock     func() time.Time
+}
+
+func NewValidator(keys KeySet) *Validator {
+	return &Validator{Keys: keys, ClockSkew: time.Minute, clock: time.Now}
+}
+
+// Validate verifies the signature of a compact serialized token and returns its claims
+func (v *Validator) Validate(token string) (Claims, error) {
+	parts := strings.Split(token, ".")
+
+	if len(parts) != 3 {
+		return nil, ErrMalformed
+	}
+
+	h := header{}
+
+	if err := decodeSegment(parts[0], &h); err != nil {
+		return nil, err
+	}
+
+	if !v.allows(h.Algorithm) {
+		return nil, ErrAlgorithm
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+
+	if err != nil {
+		return nil, ErrMalformed
+	}
+
+	keys, err := v.Keys.Keys(h.KeyID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := verifyAny(keys, h.Algorithm, []byte(parts[0]+"."+parts[1]), signature); err != nil {
+		return nil, err
+	}
+
+	claims := Claims{}
+
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, err
+	}
+
+	if err := v.validateClaims(claims); err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+func (v *Validator) allows(algorithm string) bool {
+	algorithms := v.Algorithms
+
+	if len(algorithms) == 0 {
+		algorithms = allAlgorithms
+	}
+
+	for _, a := range algorithms {
+		if a == algorithm {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (v *Validator) validateClaims(claims Claims) error {
+	clock := v.clock
+	if clock == nil {
+		clock = time.Now
+	}
+
+	now := clock()
+
+	/*
+	* Implementers MAY provide for some small leeway, usually no more than
+	* a few minutes, to account for clock skew.
+	*
+	* https://tools.ietf.org/html/rfc7519#section-4.1.4
+	 */
+	if exp, ok, err := claims.time("exp"); err != nil {
+		return err
+	} else if ok && !now.Before(exp.Add(v.ClockSkew)) {
+		return ErrExpired
+	}
+
+	if nbf, ok, err := claims.time("nbf"); err != nil {
+		return err
+	} else if ok && now.Add(v.ClockSkew).Before(nbf) {
+		return ErrNotYetValid
+	}
+
+	if len(v.Issuer) > 0 && claims.Issuer() != v.Issuer {
+		return ErrIssuer
+	}
+
+	if len(v.Audience) > 0 {
+		for _, audience := range claims.Audience() {
+			if audience == v.Audience {
+				return nil
+			}
+		}
+
+		return ErrAudience
+	}
+
+	return nil
+}
+
+func decodeSegment(segment string, target interface{}) error {
+	bytes, err := base64.RawURLEncoding.DecodeString(segment)
+
+	if err != nil {
+		return ErrMalformed
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(string(bytes)))
+	decoder.UseNumber()
+
+	if err := decoder.Decode(target); err != nil {
+		return ErrMalformed
+	}
+
+	return nil
+}
+
+func verifyAny(keys []Key, algorithm string, signingInput []byte, signature []byte) error {
+	candidates := 0
+
+	for _, key := range keys {
+		// never let the token choose a different algorithm than the key was issued for
+		if len(key.Algorithm) > 0 && key.Algorithm != algorithm {
+			continue
+		}
+
+		err := verify(key.Key, algorithm, signingInput, signature)
+
+		if err == nil {
+			return nil
+		}
+
+		if err != errKeyType {
+			candidates++
+		}
+	}
+
+	if candidates == 0 {
+		return ErrUnknownKey
+	}
+
+	return ErrSignature
+}
+
+var errKeyType = errors.New("jwt: key type does not match algorithm")
+
+func verify(key interface{}, algorithm string, signingInput []byte, signature []byte) error {
+	digest := sha256.Sum256(signingInput)
+
+	switch algorithm {
+	case HS256:
+		secret, ok := key.([]byte)
+		if !ok {
+			return errKeyType
+		}
+		mac := hmac.New(sha256.New, secret)
+		mac.Write(signingInput)
+		if !hmac.Equal(mac.Sum(nil), signature) {
+			return ErrSignature
+		}
+	case RS256:
+		public, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return errKeyType
+		}
+		if rsa.VerifyPKCS1v15(public, crypto.SHA256, digest[:], signature) != nil {
+			return ErrSignature
+		}
+	case ES256:
+		public, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return errKeyType
+		}
+		/*
+		* The ECDSA P-256 SHA-256 digital signature is the concatenation of R and S
+		* as 32 byte big endian unsigned integers
+		*
+		* https://tools.ietf.org/html/rfc7518#section-3.4
+		 */
+		if len(signature) != 64 {
+			return ErrSignature
+		}
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		if !ecdsa.Verify(public, digest[:], r, s) {
+			return ErrSignature
+		}
+	case EdDSA:
+		public, ok := key.(ed25519.PublicKey)
+		if !ok {
+			return errKeyType
+		}
+		if !ed25519.Verify(public, signingInput, signature) {
+			return ErrSignature
+		}
+	default:
+		return fmt.Errorf("jwt: unsupported algorithm %v", algorithm)
+	}
+
+	return nil
+}
@@ -0,0 +1,236 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	assert "github.com/stfsy/golang-assert"
+)
+
+var (
+	now                = time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	secret             = []byte("0123456789abcdef0123456789abcdef")
+	rsaKey, _          = rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _           = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	edPublic, edKey, _ = ed25519.GenerateKey(rand.Reader)
+)
+
+func TestValidateAllAlgorithms(t *testing.T) {
+	validator := newValidator(keySet(t))
+
+	for _, algorithm := range []string{HS256, RS256, ES256, EdDSA} {
+		token := sign(algorithm, algorithm, Claims{"sub": "mario", "exp": now.Add(time.Hour).Unix()})
+		claims, err := validator.Validate(token)
+
+		assert.Equal(t, err, nil, fmt.Sprintf("Token signed with %v is valid", algorithm))
+		assert.Equal(t, claims.Subject(), "mario", "Subject is mario")
+	}
+}
+
+func TestValidateRejectsTamperedToken(t *testing.T) {
+	validator := newValidator(keySet(t))
+	token := sign(RS256, RS256, Claims{"sub": "mario"})
+	tampered := token[:len(token)-4] + "AAAA"
+
+	_, err := validator.Validate(tampered)
+
+	assert.Equal(t, err, ErrSignature, "Tampered token is rejected")
+}
+
+func TestValidateRejectsAlgorithmConfusion(t *testing.T) {
+	validator := newValidator(keySet(t))
+	// a HS256 token claiming to be signed by the RSA key
+	token := sign(HS256, RS256, Claims{"sub": "mario"})
+
+	_, err := validator.Validate(token)
+
+	assert.Equal(t, err, ErrUnknownKey, "Key of different algorithm is not used")
+}
+
+func TestValidateRejectsNone(t *testing.T) {
+	validator := newValidator(keySet(t))
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`))
+	payload := base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"mario"}`))
+
+	_, err := validator.Validate(header + "." + payload + ".")
+
+	assert.Equal(t, err, ErrAlgorithm, "Unsigned tokens are rejected")
+}
+
+func TestValidateExpiryWithClockSkew(t *testing.T) {
+	validator := newValidator(keySet(t))
+
+	_, withinSkew := validator.Validate(sign(HS256, HS256, Claims{"exp": now.Add(-30 * time.Second).Unix()}))
+	_, expired := validator.Validate(sign(HS256, HS256, Claims{"exp": now.Add(-2 * time.Minute).Unix()}))
+
+	assert.Equal(t, withinSkew, nil, "Token expired within clock skew is valid")
+	assert.Equal(t, expired, ErrExpired, "Token expired before clock skew is invalid")
+}
+
+func TestValidateNotBefore(t *testing.T) {
+	validator := newValidator(keySet(t))
+
+	_, err := validator.Validate(sign(HS256, HS256, Claims{"nbf": now.Add(2 * time.Minute).Unix()}))
+
+	assert.Equal(t, err, ErrNotYetValid, "Token is not valid yet")
+}
+
+func TestValidateIssuerAndAudience(t *testing.T) {
+	validator := newValidator(keySet(t))
+	validator.Issuer = "https://issuer.example"
+	validator.Audience = "cable"
+
+	_, valid := validator.Validate(sign(HS256, HS256, Claims{"iss": "https://issuer.example", "aud": []string{"other", "cable"}}))
+	_, issuer := validator.Validate(sign(HS256, HS256, Claims{"iss": "https://evil.example", "aud": "cable"}))
+	_, audience := validator.Validate(sign(HS256, HS256, Claims{"iss": "https://issuer.example", "aud": "other"}))
+
+	assert.Equal(t, valid, nil, "Token with expected issuer and audience is valid")
+	assert.Equal(t, issuer, ErrIssuer, "Unexpected issuer is rejected")
+	assert.Equal(t, audience, ErrAudience, "Unexpected audience is rejected")
+}
+
+func TestFileKeySetReloadsOnUnknownKeyID(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	ioutil.WriteFile(path, jwks(t, jsonWebKey{KeyType: "oct", KeyID: "old", K: base64.RawURLEncoding.EncodeToString([]byte("old"))}), 0600)
+
+	keys := NewFileKeySet(path, time.Hour)
+	keys.clock = func() time.Time { return now }
+	validator := newValidator(keys)
+
+	_, err := validator.Validate(sign(HS256, HS256, Claims{}))
+	assert.Equal(t, err, ErrUnknownKey, "Rotated key is not known yet")
+
+	ioutil.WriteFile(path, jwks(t, jsonWebKey{KeyType: "oct", KeyID: HS256, K: base64.RawURLEncoding.EncodeToString(secret)}), 0600)
+	keys.clock = func() time.Time { return now.Add(2 * time.Minute) }
+
+	_, err = validator.Validate(sign(HS256, HS256, Claims{}))
+	assert.Equal(t, err, nil, "Rotated key is loaded")
+}
+
+func TestRemoteKeySetIsCached(t *testing.T) {
+	requests := 0
+	document := jwks(t, edJSONWebKey())
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		requests++
+		writer.Write(document)
+	}))
+	defer server.Close()
+
+	keys := NewRemoteKeySet(server.URL, time.Hour, nil)
+	keys.clock = func() time.Time { return now }
+	validator := newValidator(keys)
+
+	for i := 0; i < 3; i++ {
+		_, err := validator.Validate(sign(EdDSA, EdDSA, Claims{}))
+		assert.Equal(t, err, nil, "Token is valid")
+	}
+
+	assert.Equal(t, requests, 1, "Key set is fetched once")
+}
+
+func TestRemoteKeySetRetriesFailedLoadAfterMinRefreshInterval(t *testing.T) {
+	requests := 0
+	available := false
+	document := jwks(t, edJSONWebKey())
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		requests++
+		if !available {
+			writer.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		writer.Write(document)
+	}))
+	defer server.Close()
+
+	keys := NewRemoteKeySet(server.URL, time.Hour, nil)
+	keys.clock = func() time.Time { return now }
+	validator := newValidator(keys)
+
+	for i := 0; i < 5; i++ {
+		_, err := validator.Validate(sign(EdDSA, EdDSA, Claims{}))
+		assert.NotEqual(t, err, nil, "Token cannot be verified during the outage")
+	}
+
+	assert.Equal(t, requests, 1, "Failed load is not retried before MinRefreshInterval")
+
+	available = true
+	keys.clock = func() time.Time { return now.Add(2 * time.Minute) }
+
+	_, err := validator.Validate(sign(EdDSA, EdDSA, Claims{}))
+	assert.Equal(t, err, nil, "Token is valid after the key set recovered")
+	assert.Equal(t, requests, 2, "Key set is fetched again")
+}
+
+func newValidator(keys KeySet) *Validator {
+	validator := NewValidator(keys)
+	validator.clock = func() time.Time { return now }
+	return validator
+}
+
+func keySet(t *testing.T) KeySet {
+	keys, err := ParseKeySet(jwks(t,
+		jsonWebKey{KeyType: "oct", KeyID: HS256, Algorithm: HS256, K: base64.RawURLEncoding.EncodeToString(secret)},
+		jsonWebKey{KeyType: "RSA", KeyID: RS256, Algorithm: RS256,
+			N: base64.RawURLEncoding.EncodeToString(rsaKey.N.Bytes()),
+			E: base64.RawURLEncoding.EncodeToString(big.NewInt(int64(rsaKey.E)).Bytes())},
+		jsonWebKey{KeyType: "EC", KeyID: ES256, Curve: "P-256",
+			X: base64.RawURLEncoding.EncodeToString(ecKey.X.FillBytes(make([]byte, 32))),
+			Y: base64.RawURLEncoding.EncodeToString(ecKey.Y.FillBytes(make([]byte, 32)))},
+		edJSONWebKey()))
+
+	assert.Equal(t, err, nil, "Key set is valid")
+	assert.Equal(t, len(keys), 4, "Key set has 4 keys")
+
+	return keys
+}
+
+func edJSONWebKey() jsonWebKey {
+	return jsonWebKey{KeyType: "OKP", KeyID: EdDSA, Curve: "Ed25519", X: base64.RawURLEncoding.EncodeToString(edPublic)}
+}
+
+func jwks(t *testing.T, keys ...jsonWebKey) []byte {
+	document, err := json.Marshal(jsonWebKeySet{Keys: keys})
+	assert.Equal(t, err, nil, "Key set is serialized")
+	return document
+}
+
+func sign(algorithm string, kid string, claims Claims) string {
+	header, _ := json.Marshal(map[string]string{"alg": algorithm, "kid": kid, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	input := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(input))
+
+	var signature []byte
+
+	switch algorithm {
+	case HS256:
+		mac := hmac.New(sha256.New, secret)
+		mac.Write([]byte(input))
+		signature = mac.Sum(nil)
+	case RS256:
+		signature, _ = rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA256, digest[:])
+	case ES256:
+		r, s, _ := ecdsa.Sign(rand.Reader, ecKey, digest[:])
+		signature = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	case EdDSA:
+		signature = ed25519.Sign(edKey, []byte(input))
+	}
+
+	return input + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
@@ -0,0 +1,32 @@
+package cable
+
+import (
+	"github.com/stfsy/golang-cable/cable/jwt"
+)
+
+// JWTVerifier resolves principals from bearer tokens. The sub claim becomes the
+// name of the principal, the scope claim its scopes.
+type JWTVerifier struct {
+	Validator        *jwt.Validator
+	RolesClaim       string
+	PermissionsClaim string
+}
+
+func NewJWTVerifier(validator *jwt.Validator) *JWTVerifier {
+	return &JWTVerifier{Validator: validator, RolesClaim: "roles", PermissionsClaim: "permissions"}
+}
+
+func (j *JWTVerifier) Verify(token string) (*Principal, error) {
+	claims, err := j.Validator.Validate(token)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &Principal{
+		Name:        claims.Subject(),
+		Roles:       claims.Strings(j.RolesClaim),
+		Scopes:      claims.Strings("scope"),
+		Permissions: claims.Strings(j.PermissionsClaim),
+		Claims:      claims}, nil
+}
@@ -0,0 +1,65 @@
+package cable
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	assert "github.com/stfsy/golang-assert"
+	"github.com/stfsy/golang-cable/cable/jwt"
+)
+
+var jwtSecret = []byte("0123456789abcdef0123456789abcdef")
+
+func TestJWTVerifierResolvesPrincipalAndClaims(t *testing.T) {
+	reset()
+	registerJWTAuthentication()
+	Get("/claims", func(req RequestEntity, resp *ResponseEntity) {
+		resp.Status = 200
+		resp.Body = []byte(req.Principal.Name + " " + strings.Join(req.Principal.Roles, ",") + " " + req.Principal.Claims["tenant"].(string))
+	})
+
+	request := httptest.NewRequest(http.MethodGet, "/claims", strings.NewReader(""))
+	request.Header.Set("Authorization", "Bearer "+signHS256(`{"sub":"mario","roles":["plumber","hero"],"tenant":"mushroom"}`))
+	recorder := httptest.NewRecorder()
+
+	HandleRequest(recorder, request)
+
+	assert.Equal(t, recorder.Code, 200, "Response status code is 200")
+	assert.Equal(t, recorder.Body.String(), "mario plumber,hero mushroom", "Response body includes principal, roles and claims")
+}
+
+func TestJWTVerifierRejectsExpiredToken(t *testing.T) {
+	reset()
+	registerJWTAuthentication()
+	registerGetPersons()
+
+	request := httptest.NewRequest(http.MethodGet, "/persons", strings.NewReader(""))
+	request.Header.Set("Authorization", "Bearer "+signHS256(`{"sub":"mario","exp":1}`))
+	recorder := httptest.NewRecorder()
+
+	HandleRequest(recorder, request)
+
+	assert.Equal(t, recorder.Code, 401, "Response status code is 401")
+	assert.Equal(t, recorder.Header().Get("WWW-Authenticate"), "Bearer realm=\"cable\", error=\"invalid_token\"", "Bearer challenge has invalid_token error")
+}
+
+func registerJWTAuthentication() {
+	validator := jwt.NewValidator(jwt.StaticKeySet{jwt.Key{Algorithm: jwt.HS256, Key: jwtSecret}})
+	authentication := NewAuthentication(NewBearerAuthenticator("cable", NewJWTVerifier(validator)))
+
+	Filter("/.*", authentication.Filter)
+}
+
+func signHS256(claims string) string {
+	input := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`)) + "." +
+		base64.RawURLEncoding.EncodeToString([]byte(claims))
+	mac := hmac.New(sha256.New, jwtSecret)
+	mac.Write([]byte(input))
+
+	return input + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,79 @@
+package cable
+
+import (
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	passwordHashScheme = "pbkdf2-sha256"
+	passwordSaltLength = 16
+	passwordKeyLength  = 32
+)
+
+var (
+	// https://cheatsheetseries.owasp.org/cheatsheets/Password_Storage_Cheat_Sheet.html#pbkdf2
+	passwordHashIterations = 600000
+	unknownUserHash        string
+	unknownUserHashOnce    sync.Once
+)
+
+// HashPassword derives a salted hash to be stored in a CredentialStore
+func HashPassword(password string) (string, error) {
+	salt := make([]byte, passwordSaltLength)
+
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key, err := pbkdf2.Key(sha256.New, password, salt, passwordHashIterations, passwordKeyLength)
+
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s$%d$%s$%s", passwordHashScheme, passwordHashIterations,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// VerifyPassword compares a password with a hash created by HashPassword
+func VerifyPassword(hash string, password string) bool {
+	parts := strings.Split(hash, "$")
+
+	if len(parts) != 4 || parts[0] != passwordHashScheme {
+		return false
+	}
+
+	iterations, err := strconv.Atoi(parts[1])
+	salt, saltErr := base64.RawStdEncoding.DecodeString(parts[2])
+	expected, keyErr := base64.RawStdEncoding.DecodeString(parts[3])
+
+	if err != nil || saltErr != nil || keyErr != nil {
+		return false
+	}
+
+	key, err := pbkdf2.Key(sha256.New, password, salt, iterations, len(expected))
+
+	if err != nil {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare(key, expected) == 1
+}
+
+// verifyUnknownUser costs as much time as verifying the password of a known user
+func verifyUnknownUser(password string) {
+	unknownUserHashOnce.Do(func() {
+		unknownUserHash, _ = HashPassword("")
+	})
+
+	VerifyPassword(unknownUserHash, password)
+}
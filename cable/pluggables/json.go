@@ -10,14 +10,14 @@ type JsonPlugin struct {
 
 var (
 	jsonConsumes = []string{"*/*", "application/*", "application/json"}
-	jsonProduces = []string{"*/*", "application/*", "application/json"}
+	jsonProduces = []string{"*/*", "application/*", "application/json", "application/problem+json"}
 )
 
 func (j JsonPlugin) Consumes() []string { return jsonConsumes }
 func (j JsonPlugin) Produces() []string { return jsonProduces }
 
-func (j JsonPlugin) Consume(b []byte, i *interface{}) error {
-	return json.Unmarshal(b, i)
+func (j JsonPlugin) Consume(b []byte, target interface{}) error {
+	return json.Unmarshal(b, target)
 }
 
 func (j JsonPlugin) Produce(i interface{}) ([]byte, error) {
@@ -1,8 +1,10 @@
 package pluggables
 
+// Plugin marshals and unmarshals bodies of the media types it produces and consumes.
+// Consume decodes into target, which is a non-nil pointer.
 type Plugin interface {
 	Consumes() []string
 	Produces() []string
-	Consume(b []byte, i *interface{}) error
+	Consume(b []byte, target interface{}) error
 	Produce(i interface{}) ([]byte, error)
 }
@@ -10,14 +10,14 @@ type XmlPlugin struct {
 
 var (
 	xmlConsumes = []string{"application/xml"}
-	xmlProduces = []string{"application/xml"}
+	xmlProduces = []string{"application/xml", "application/problem+xml"}
 )
 
 func (x XmlPlugin) Consumes() []string { return xmlConsumes }
 func (x XmlPlugin) Produces() []string { return xmlProduces }
 
-func (x XmlPlugin) Consume(b []byte, i *interface{}) error {
-	return xml.Unmarshal(b, i)
+func (x XmlPlugin) Consume(b []byte, target interface{}) error {
+	return xml.Unmarshal(b, target)
 }
 
 func (x XmlPlugin) Produce(i interface{}) ([]byte, error) {
@@ -0,0 +1,123 @@
+package cable
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Version identifies the current state of a resource. Either field may be empty.
+type Version struct {
+	// ETag as sent in responses, e.g. "v42" including quotes
+	ETag         string
+	LastModified time.Time
+}
+
+// VersionFunc looks up the current version of the resource addressed by request and
+// returns false if it does not exist
+type VersionFunc func(request *http.Request) (Version, bool)
+
+// Preconditions evaluates If-Match and If-Unmodified-Since of PUT, PATCH and DELETE
+// requests before the request handler runs.
+type Preconditions struct {
+	Version VersionFunc
+	// Required responds 428 Precondition Required to requests without precondition,
+	// preventing lost updates by clients unaware of concurrent modifications
+	Required bool
+}
+
+// NewPreconditions looks up versions with version. Register it with
+// cable.Intercept(pattern, preconditions.Intercept).
+func NewPreconditions(version VersionFunc) *Preconditions {
+	return &Preconditions{Version: version}
+}
+
+func (p *Preconditions) Intercept(requestEntity RequestEntity, response *ResponseEntity, next func(requestEntity RequestEntity, response *ResponseEntity)) {
+	request := requestEntity.Request
+
+	switch request.Method {
+	case http.MethodPut, http.MethodPatch, http.MethodDelete:
+	default:
+		next(requestEntity, response)
+		return
+	}
+
+	ifMatch := request.Header.Get("If-Match")
+	ifUnmodifiedSince := request.Header.Get("If-Unmodified-Since")
+
+	if len(ifMatch) == 0 && len(ifUnmodifiedSince) == 0 {
+		if p.Required {
+			setError(response, request, http.StatusPreconditionRequired, "request must be conditional")
+			return
+		}
+
+		next(requestEntity, response)
+		return
+	}
+
+	version, exists := p.Version(request)
+
+	if !preconditionsHold(ifMatch, ifUnmodifiedSince, version, exists) {
+		logger.Info("Precondition failed",
+			zap.String("Path", request.URL.Path),
+			zap.String("Method", request.Method),
+			zap.String("ETag", version.ETag))
+
+		setError(response, request, http.StatusPreconditionFailed, "resource has been modified")
+		return
+	}
+
+	next(requestEntity, response)
+}
+
+/*
+* An origin server MUST use the strong comparison function when comparing entity-tags
+* for If-Match, since the client intends this precondition to prevent the method from
+* being applied if there have been any changes to the representation data.
+*
+* A recipient MUST ignore If-Unmodified-Since if the request contains an If-Match
+* header field.
+*
+* https://tools.ietf.org/html/rfc7232#section-3.1
+ */
+func preconditionsHold(ifMatch string, ifUnmodifiedSince string, version Version, exists bool) bool {
+	if len(ifMatch) > 0 {
+		for _, tag := range parseETags(ifMatch) {
+			if (tag == "*" && exists) || (exists && strongMatch(tag, version.ETag)) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	unmodifiedSince, err := http.ParseTime(ifUnmodifiedSince)
+
+	// an invalid date or unknown modification date must be ignored
+	if err != nil || !exists || version.LastModified.IsZero() {
+		return true
+	}
+
+	return !version.LastModified.Truncate(time.Second).After(unmodifiedSince)
+}
+
+/*
+* Compression appends the content coding to entity-tags of encoded representations.
+* Clients send these tags back in If-Match, while the version refers to the unencoded
+* representation stored by the application.
+ */
+func strongMatch(a string, b string) bool {
+	if strings.HasPrefix(a, "W/") {
+		return false
+	}
+
+	if a == b {
+		return true
+	}
+
+	decoded, ok := decodedETag(a)
+
+	return ok && decoded == b
+}
@@ -0,0 +1,128 @@
+package cable
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	assert "github.com/stfsy/golang-assert"
+)
+
+func TestPreconditionsMatchingETagResponds200(t *testing.T) {
+	reset()
+	registerPreconditions(false)
+	registerPutPersons()
+
+	recorder := putConditional(map[string]string{"If-Match": `"v1", "v2"`})
+
+	assert.Equal(t, recorder.Code, 200, "Response status code is 200")
+}
+
+func TestPreconditionsOutdatedETagResponds412(t *testing.T) {
+	reset()
+	registerPreconditions(false)
+	registerPutPersons()
+
+	recorder := putConditional(map[string]string{"If-Match": `"v1"`})
+
+	assert.Equal(t, recorder.Code, 412, "Response status code is 412")
+	assert.Equal(t, recorder.Body.String(), `{"type":"about:blank","title":"Precondition Failed","status":412,"detail":"resource has been modified","instance":"/persons"}`, "Response body describes the error")
+}
+
+func TestPreconditionsWeakETagResponds412(t *testing.T) {
+	reset()
+	registerPreconditions(false)
+	registerPutPersons()
+
+	recorder := putConditional(map[string]string{"If-Match": `W/"v2"`})
+
+	assert.Equal(t, recorder.Code, 412, "Weak tags never match strongly")
+}
+
+func TestPreconditionsIfUnmodifiedSince(t *testing.T) {
+	reset()
+	registerPreconditions(false)
+	registerPutPersons()
+
+	current := putConditional(map[string]string{"If-Unmodified-Since": epoch.Format(http.TimeFormat)})
+	outdated := putConditional(map[string]string{"If-Unmodified-Since": epoch.Add(-time.Hour).Format(http.TimeFormat)})
+
+	assert.Equal(t, current.Code, 200, "Response status code is 200")
+	assert.Equal(t, outdated.Code, 412, "Response status code is 412")
+}
+
+func TestPreconditionsMissingHeaderResponds428(t *testing.T) {
+	reset()
+	registerPreconditions(true)
+	registerPutPersons()
+
+	recorder := putConditional(nil)
+
+	assert.Equal(t, recorder.Code, 428, "Response status code is 428")
+}
+
+func TestPreconditionsOptionalHeaderResponds200(t *testing.T) {
+	reset()
+	registerPreconditions(false)
+	registerPutPersons()
+
+	recorder := putConditional(nil)
+
+	assert.Equal(t, recorder.Code, 200, "Response status code is 200")
+}
+
+func TestPreconditionsMatchingCompressedETagResponds200(t *testing.T) {
+	reset()
+	registerPreconditions(false)
+	registerPutPersons()
+	Intercept("/.*", NewCompression().Intercept)
+	Get("/persons", func(req RequestEntity, resp *ResponseEntity) {
+		resp.Status = 200
+		resp.Header = map[string]string{"Content-Type": "application/json", "Etag": `"v2"`}
+		resp.Body = []byte(largeBody)
+	})
+
+	request := httptest.NewRequest(http.MethodGet, "/persons", nil)
+	request.Header.Set("Accept-Encoding", "gzip")
+	get := httptest.NewRecorder()
+	HandleRequest(get, request)
+
+	put := putConditional(map[string]string{"If-Match": get.Header().Get("Etag")})
+
+	assert.Equal(t, get.Header().Get("Etag"), `"v2-gzip"`, "ETag contains content coding")
+	assert.Equal(t, put.Code, 200, "Response status code is 200")
+}
+
+func TestPreconditionsUnknownETagSuffixResponds412(t *testing.T) {
+	reset()
+	registerPreconditions(false)
+	registerPutPersons()
+
+	recorder := putConditional(map[string]string{"If-Match": `"v2-unknown"`})
+
+	assert.Equal(t, recorder.Code, 412, "Response status code is 412")
+}
+
+func registerPreconditions(required bool) {
+	preconditions := NewPreconditions(func(request *http.Request) (Version, bool) {
+		return Version{ETag: `"v2"`, LastModified: epoch}, true
+	})
+	preconditions.Required = required
+	Intercept("/persons", preconditions.Intercept)
+}
+
+func putConditional(header map[string]string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(http.MethodPut, "/persons", strings.NewReader("[]"))
+
+	for key, value := range header {
+		request.Header.Set(key, value)
+	}
+
+	recorder := httptest.NewRecorder()
+
+	HandleRequest(recorder, request)
+
+	return recorder
+}
@@ -0,0 +1,292 @@
+package cable
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	retryAfterHeader         = "Retry-After"
+	rateLimitLimitHeader     = "RateLimit-Limit"
+	rateLimitRemainingHeader = "RateLimit-Remaining"
+	rateLimitResetHeader     = "RateLimit-Reset"
+	rateLimitPolicyHeader    = "RateLimit-Policy"
+)
+
+// RateLimit allows Requests per Window for a single key
+type RateLimit struct {
+	Requests int
+	Window   time.Duration
+}
+
+// RateLimitDecision is the result of taking one request from a RateLimitStore
+type RateLimitDecision struct {
+	Allowed   bool
+	Remaining int
+	// Reset is the time until the quota is fully available again
+	Reset time.Duration
+	// RetryAfter is the time until the next request will be allowed, if this one was not
+	RetryAfter time.Duration
+}
+
+// RateLimitStore keeps the quota per key. Implementations must be safe for concurrent use.
+type RateLimitStore interface {
+	Take(key string, limit RateLimit, now time.Time) (RateLimitDecision, error)
+}
+
+// RateLimitKeyFunc returns the key a request is counted against
+type RateLimitKeyFunc func(request *http.Request) string
+
+type RateLimiter struct {
+	Limit RateLimit
+	Key   RateLimitKeyFunc
+	Store RateLimitStore
+	clock func() time.Time
+}
+
+// NewRateLimiter creates a limiter counting requests per client ip in a token bucket.
+// Register it for a route or a group of routes with cable.Filter(pattern, limiter.Filter).
+func NewRateLimiter(limit RateLimit) *RateLimiter {
+	return &RateLimiter{
+		Limit: limit,
+		Key:   ClientIPKey,
+		Store: NewTokenBucketStore(),
+		clock: time.Now}
+}
+
+func (l *RateLimiter) Filter(writer http.ResponseWriter, request *http.Request) {
+	key := ""
+
+	if l.Key != nil {
+		key = l.Key(request)
+	}
+
+	// requests without e.g. an api key are still counted, but per client
+	if len(key) == 0 {
+		key = ClientIPKey(request)
+	}
+
+	clock := l.clock
+	if clock == nil {
+		clock = time.Now
+	}
+
+	decision, err := l.Store.Take(key, l.Limit, clock())
+
+	if err != nil {
+		// rather serve the request than fail because the store is unavailable
+		logger.Error("Rate limit store failed",
+			zap.String("Path", request.URL.Path),
+			zap.Error(err))
+		return
+	}
+
+	/*
+	* RateLimit header fields for HTTP
+	*
+	* https://datatracker.ietf.org/doc/draft-ietf-httpapi-ratelimit-headers/
+	 */
+	header := writer.Header()
+	header.Set(rateLimitLimitHeader, strconv.Itoa(l.Limit.Requests))
+	header.Set(rateLimitRemainingHeader, strconv.Itoa(decision.Remaining))
+	header.Set(rateLimitResetHeader, strconv.Itoa(ceilSeconds(decision.Reset)))
+	header.Set(rateLimitPolicyHeader, fmt.Sprintf("%d;w=%d", l.Limit.Requests, ceilSeconds(l.Limit.Window)))
+
+	if !decision.Allowed {
+		logger.Info("Rate limit exceeded",
+			zap.String("Path", request.URL.Path),
+			zap.String("Key", key))
+
+		header.Set(retryAfterHeader, strconv.Itoa(ceilSeconds(decision.RetryAfter)))
+		writeError(writer, request, http.StatusTooManyRequests, "rate limit exceeded")
+	}
+}
+
+// ClientIPKey counts requests per client, resolved through trusted proxies
+func ClientIPKey(request *http.Request) string {
+	return ClientIP(request)
+}
+
+// APIKeyKey counts requests per api key, read from the given header or query parameter
+func APIKeyKey(header string, queryParam string) RateLimitKeyFunc {
+	return func(request *http.Request) string {
+		if key := request.Header.Get(header); len(key) > 0 {
+			return "apikey:" + key
+		}
+
+		if len(queryParam) > 0 {
+			if key := request.URL.Query().Get(queryParam); len(key) > 0 {
+				return "apikey:" + key
+			}
+		}
+
+		return ""
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	if d <= 0 {
+		return 0
+	}
+
+	return int(math.Ceil(d.Seconds()))
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+type tokenBucketStore struct {
+	mutex     sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+// NewTokenBucketStore keeps one in-memory token bucket per key. A bucket holds up to
+// limit.Requests tokens and refills continuously over limit.Window.
+func NewTokenBucketStore() RateLimitStore {
+	return &tokenBucketStore{buckets: map[string]*tokenBucket{}}
+}
+
+func (s *tokenBucketStore) Take(key string, limit RateLimit, now time.Time) (RateLimitDecision, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	capacity := float64(limit.Requests)
+	rate := capacity / float64(limit.Window)
+
+	s.sweep(limit, now)
+
+	bucket, ok := s.buckets[key]
+
+	if !ok {
+		bucket = &tokenBucket{tokens: capacity, last: now}
+		s.buckets[key] = bucket
+	}
+
+	if elapsed := now.Sub(bucket.last); elapsed > 0 {
+		bucket.tokens = math.Min(capacity, bucket.tokens+float64(elapsed)*rate)
+		bucket.last = now
+	}
+
+	decision := RateLimitDecision{}
+
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		decision.Allowed = true
+	} else {
+		decision.RetryAfter = time.Duration((1 - bucket.tokens) / rate)
+	}
+
+	decision.Remaining = int(math.Floor(bucket.tokens))
+	decision.Reset = time.Duration((capacity - bucket.tokens) / rate)
+
+	return decision, nil
+}
+
+func (s *tokenBucketStore) sweep(limit RateLimit, now time.Time) {
+	if now.Sub(s.lastSweep) < limit.Window {
+		return
+	}
+
+	// a bucket untouched for a whole window is full again, forget it
+	for key, bucket := range s.buckets {
+		if now.Sub(bucket.last) >= limit.Window {
+			delete(s.buckets, key)
+		}
+	}
+
+	s.lastSweep = now
+}
+
+type slidingWindow struct {
+	start    time.Time
+	current  int
+	previous int
+}
+
+type slidingWindowStore struct {
+	mutex     sync.Mutex
+	windows   map[string]*slidingWindow
+	lastSweep time.Time
+}
+
+// NewSlidingWindowStore keeps an in-memory sliding window counter per key. The count of
+// the previous window is weighted by its overlap with the sliding window.
+func NewSlidingWindowStore() RateLimitStore {
+	return &slidingWindowStore{windows: map[string]*slidingWindow{}}
+}
+
+func (s *slidingWindowStore) Take(key string, limit RateLimit, now time.Time) (RateLimitDecision, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.sweep(limit, now)
+
+	start := now.Truncate(limit.Window)
+	window, ok := s.windows[key]
+
+	if !ok {
+		window = &slidingWindow{start: start}
+		s.windows[key] = window
+	}
+
+	if !window.start.Equal(start) {
+		if start.Sub(window.start) == limit.Window {
+			window.previous = window.current
+		} else {
+			window.previous = 0
+		}
+		window.current = 0
+		window.start = start
+	}
+
+	elapsed := now.Sub(start)
+	weight := 1 - float64(elapsed)/float64(limit.Window)
+	estimate := float64(window.previous)*weight + float64(window.current)
+
+	decision := RateLimitDecision{Reset: limit.Window - elapsed}
+
+	if estimate+1 <= float64(limit.Requests) {
+		window.current++
+		estimate++
+		decision.Allowed = true
+	} else if window.current >= limit.Requests || window.previous == 0 {
+		decision.RetryAfter = limit.Window - elapsed
+	} else {
+		// the weight of the previous window drops until one more request fits
+		excess := estimate + 1 - float64(limit.Requests)
+		retryAfter := time.Duration(excess / float64(window.previous) * float64(limit.Window))
+
+		if retryAfter > limit.Window-elapsed {
+			retryAfter = limit.Window - elapsed
+		}
+
+		decision.RetryAfter = retryAfter
+	}
+
+	decision.Remaining = int(math.Max(0, math.Floor(float64(limit.Requests)-estimate)))
+
+	return decision, nil
+}
+
+func (s *slidingWindowStore) sweep(limit RateLimit, now time.Time) {
+	if now.Sub(s.lastSweep) < limit.Window {
+		return
+	}
+
+	for key, window := range s.windows {
+		if now.Sub(window.start) >= 2*limit.Window {
+			delete(s.windows, key)
+		}
+	}
+
+	s.lastSweep = now
+}
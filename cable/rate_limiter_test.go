@@ -0,0 +1,116 @@
+package cable
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	assert "github.com/stfsy/golang-assert"
+)
+
+var epoch = time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func TestRateLimitAllowsRequestsWithinLimit(t *testing.T) {
+	reset()
+	registerGetPersons()
+	registerRateLimiter(NewRateLimiter(RateLimit{Requests: 2, Window: time.Minute}))
+
+	recorder := getPersonsFrom("192.0.2.1:1234")
+
+	assert.Equal(t, recorder.Code, 200, "Response status code is 200")
+	assert.Equal(t, recorder.Header().Get("RateLimit-Limit"), "2", "RateLimit-Limit is 2")
+	assert.Equal(t, recorder.Header().Get("RateLimit-Remaining"), "1", "RateLimit-Remaining is 1")
+	assert.Equal(t, recorder.Header().Get("RateLimit-Policy"), "2;w=60", "RateLimit-Policy is 2;w=60")
+}
+
+func TestRateLimitResponds429WithRetryAfter(t *testing.T) {
+	reset()
+	registerGetPersons()
+	registerRateLimiter(NewRateLimiter(RateLimit{Requests: 2, Window: time.Minute}))
+
+	getPersonsFrom("192.0.2.1:1234")
+	getPersonsFrom("192.0.2.1:1234")
+	recorder := getPersonsFrom("192.0.2.1:1234")
+
+	assert.Equal(t, recorder.Code, 429, "Response status code is 429")
+	assert.Equal(t, recorder.Header().Get("Retry-After"), "30", "Retry-After is 30 seconds")
+	assert.Equal(t, recorder.Header().Get("RateLimit-Remaining"), "0", "RateLimit-Remaining is 0")
+}
+
+func TestRateLimitCountsClientsSeparately(t *testing.T) {
+	reset()
+	registerGetPersons()
+	registerRateLimiter(NewRateLimiter(RateLimit{Requests: 1, Window: time.Minute}))
+
+	getPersonsFrom("192.0.2.1:1234")
+	recorder := getPersonsFrom("192.0.2.2:1234")
+
+	assert.Equal(t, recorder.Code, 200, "Response status code is 200")
+}
+
+func TestRateLimitByAPIKey(t *testing.T) {
+	reset()
+	registerGetPersons()
+	limiter := NewRateLimiter(RateLimit{Requests: 1, Window: time.Minute})
+	limiter.Key = APIKeyKey("X-Api-Key", "api_key")
+	registerRateLimiter(limiter)
+
+	first := httptest.NewRequest(http.MethodGet, "/persons", strings.NewReader(""))
+	first.Header.Set("X-Api-Key", "abc")
+	HandleRequest(httptest.NewRecorder(), first)
+
+	second := httptest.NewRequest(http.MethodGet, "/persons?api_key=abc", strings.NewReader(""))
+	second.RemoteAddr = "192.0.2.99:1234"
+	recorder := httptest.NewRecorder()
+	HandleRequest(recorder, second)
+
+	assert.Equal(t, recorder.Code, 429, "Response status code is 429")
+}
+
+func TestTokenBucketRefills(t *testing.T) {
+	store := NewTokenBucketStore()
+	limit := RateLimit{Requests: 2, Window: time.Minute}
+
+	store.Take("a", limit, epoch)
+	store.Take("a", limit, epoch)
+	denied, _ := store.Take("a", limit, epoch)
+	allowed, _ := store.Take("a", limit, epoch.Add(30*time.Second))
+
+	assert.Equal(t, denied.Allowed, false, "Third request is denied")
+	assert.Equal(t, denied.RetryAfter, 30*time.Second, "One token is refilled after 30 seconds")
+	assert.Equal(t, allowed.Allowed, true, "Request is allowed after refill")
+}
+
+func TestSlidingWindowWeightsPreviousWindow(t *testing.T) {
+	store := NewSlidingWindowStore()
+	limit := RateLimit{Requests: 4, Window: time.Minute}
+
+	for i := 0; i < 4; i++ {
+		store.Take("a", limit, epoch.Add(50*time.Second))
+	}
+
+	// 3 of 4 requests of the previous window are still counted
+	allowed, _ := store.Take("a", limit, epoch.Add(75*time.Second))
+	denied, _ := store.Take("a", limit, epoch.Add(75*time.Second))
+
+	assert.Equal(t, allowed.Allowed, true, "First request of new window is allowed")
+	assert.Equal(t, denied.Allowed, false, "Second request of new window is denied")
+	assert.Equal(t, denied.RetryAfter, 15*time.Second, "Previous window weighs less after 15 seconds")
+}
+
+func registerRateLimiter(limiter *RateLimiter) {
+	limiter.clock = func() time.Time { return epoch }
+	Filter("/persons", limiter.Filter)
+}
+
+func getPersonsFrom(remoteAddr string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(http.MethodGet, "/persons", strings.NewReader(""))
+	request.RemoteAddr = remoteAddr
+	recorder := httptest.NewRecorder()
+
+	HandleRequest(recorder, request)
+
+	return recorder
+}
@@ -4,12 +4,10 @@ package cable
 * #  ROADMAP
 * ## Content-Type
 * 1. Do not ignore params of Content-Type header. E.g. check encoding parameter
-* 2. Do not ignore Content-Encoding header. http://greenbytes.de/tech/webdav/rfc2616.html#rfc.section.14.11
-* 3. Want to check http://greenbytes.de/tech/webdav/rfc2616.html#rfc.section.14.15 ?
+* 2. Want to check http://greenbytes.de/tech/webdav/rfc2616.html#rfc.section.14.15 ?
 *
 * ## Accept
 * 1. Accept-Charset
-* 2. Accept-Encoding
  */
 
 import (
@@ -19,11 +17,13 @@ import (
 	"mime"
 	"net/http"
 	"net/textproto"
+	"reflect"
 	"sort"
 	"strings"
 
 	"github.com/stfsy/golang-cable/cable/pluggables"
 	"github.com/stfsy/golang-cable/cable/sortables"
+	"go.uber.org/zap"
 )
 
 const (
@@ -31,12 +31,25 @@ const (
 )
 
 var (
+	// ErrUnsupportedMediaType is returned when reading a request body no plugin consumes
+	ErrUnsupportedMediaType = errors.New("unsupported media type")
+	// ErrNotAcceptable is returned when no plugin produces a media type the client accepts
+	ErrNotAcceptable = errors.New("none of the accepted media types can be produced")
+	// ErrInvalidTarget is returned when unmarshalling into a value that is not a non-nil pointer
+	ErrInvalidTarget = errors.New("target must be a non-nil pointer")
+
 	contentTypeHeader = textproto.CanonicalMIMEHeaderKey("content-type")
 	acceptHeader      = textproto.CanonicalMIMEHeaderKey("accept")
 	plugins           = []pluggables.Plugin{pluggables.JsonPlugin{}, pluggables.XmlPlugin{}}
 )
 
-func UnmarshalBody(target interface{}, request *http.Request) (interface{}, error) {
+// UnmarshalBody decodes the request body into target, which must be a non-nil pointer,
+// e.g. &Person{} or &[]Person{}
+func UnmarshalBody(target interface{}, request *http.Request) error {
+	if value := reflect.ValueOf(target); value.Kind() != reflect.Ptr || value.IsNil() {
+		return fmt.Errorf("%w, got %T", ErrInvalidTarget, target)
+	}
+
 	contentTypes := request.Header[contentTypeHeader]
 
 	/*
@@ -57,6 +70,11 @@ func UnmarshalBody(target interface{}, request *http.Request) (interface{}, erro
 
 	body, err := ioutil.ReadAll(request.Body)
 
+	var maxBytesError *http.MaxBytesError
+	if errors.As(err, &maxBytesError) {
+		return ErrBodyTooLarge
+	}
+
 	if err == nil {
 
 		handled := false
@@ -65,7 +83,7 @@ func UnmarshalBody(target interface{}, request *http.Request) (interface{}, erro
 		for _, plugin := range plugins {
 			for _, consumes := range plugin.Consumes() {
 				if parsedContentType == consumes {
-					err = plugin.Consume(body, &target)
+					err = plugin.Consume(body, target)
 					handled = true
 					break loop
 				}
@@ -73,16 +91,34 @@ func UnmarshalBody(target interface{}, request *http.Request) (interface{}, erro
 		}
 
 		if !handled {
-			msg := fmt.Sprintf("Unmarshalling failed, no marshaller for content type %v", contentType)
-			err = errors.New(msg)
-			logger.Error(msg)
+			err = fmt.Errorf("%w: no marshaller for content type %v", ErrUnsupportedMediaType, contentType)
+			logger.Error("Unmarshalling failed", zap.Error(err))
 		}
 	}
 
-	return target, err
+	return err
 }
 
 func MarshalBody(target interface{}, request *http.Request) ([]byte, error) {
+	body, _, err := marshalBody(target, request)
+	return body, err
+}
+
+// marshalBody also returns the content type of the body
+func marshalBody(target interface{}, request *http.Request) ([]byte, string, error) {
+	plugin, err := negotiatePlugin(request)
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	body, err := plugin.Produce(target)
+
+	return body, concreteMediaType(plugin), err
+}
+
+// negotiatePlugin returns the plugin producing the media type preferred by the client
+func negotiatePlugin(request *http.Request) (pluggables.Plugin, error) {
 	/* Accessing the map directy means we have to use the correct casing */
 	contentTypes := request.Header[acceptHeader]
 	sortableMediaTypes := sortables.SortableMediaTypes{}
@@ -116,11 +152,22 @@ func MarshalBody(target interface{}, request *http.Request) ([]byte, error) {
 		for _, plugin := range plugins {
 			for _, produces := range plugin.Produces() {
 				if mediaTypeAndParams.Mediatype == produces {
-					return plugin.Produce(target)
+					return plugin, nil
 				}
 			}
 		}
 	}
 
-	return nil, fmt.Errorf("No Marshaller found for acceptable content types")
+	return nil, ErrNotAcceptable
+}
+
+// concreteMediaType is the first media type a plugin produces that is not a wildcard
+func concreteMediaType(plugin pluggables.Plugin) string {
+	for _, produces := range plugin.Produces() {
+		if !strings.Contains(produces, "*") {
+			return produces
+		}
+	}
+
+	return "application/octet-stream"
 }
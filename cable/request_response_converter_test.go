@@ -1,6 +1,8 @@
 package cable
 
 import (
+	"encoding/xml"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -181,12 +183,56 @@ func TestMarshallErrUnknownMediatype(t *testing.T) {
 	assert.NotEqual(t, err, nil, "Error should not be nil")
 }
 
+func TestUnmarshallJsonSlice(t *testing.T) {
+	request := httptest.NewRequest(http.MethodPost, "/persons", strings.NewReader("["+jsonBody+","+jsonBody+"]"))
+	request.Header.Add("content-type", "application/json")
+
+	persons := []Person{}
+	err := UnmarshalBody(&persons, request)
+
+	assert.Equal(t, err, nil, "Error should be nil")
+	assert.Equal(t, persons, []Person{{"Mario", "Micelli"}, {"Mario", "Micelli"}}, "Slice should be filled")
+}
+
+func TestUnmarshallXmlSlice(t *testing.T) {
+	request := httptest.NewRequest(http.MethodPost, "/persons", strings.NewReader("<persons>"+xmlBody+xmlBody+"</persons>"))
+	request.Header.Add("content-type", "application/xml")
+
+	persons := struct {
+		XMLName xml.Name `xml:"persons"`
+		Persons []Person `xml:"Person"`
+	}{}
+	err := UnmarshalBody(&persons, request)
+
+	assert.Equal(t, err, nil, "Error should be nil")
+	assert.Equal(t, persons.Persons, []Person{{"Mario", "Micelli"}, {"Mario", "Micelli"}}, "Slice should be filled")
+}
+
+func TestUnmarshallIntoValueFails(t *testing.T) {
+	request := httptest.NewRequest(http.MethodPost, "/persons", strings.NewReader(jsonBody))
+	request.Header.Add("content-type", "application/json")
+
+	err := UnmarshalBody(Person{}, request)
+
+	assert.Equal(t, errors.Is(err, ErrInvalidTarget), true, "Error should be ErrInvalidTarget")
+}
+
+func TestUnmarshallIntoNilPointerFails(t *testing.T) {
+	request := httptest.NewRequest(http.MethodPost, "/persons", strings.NewReader(jsonBody))
+	request.Header.Add("content-type", "application/json")
+
+	var person *Person
+	err := UnmarshalBody(person, request)
+
+	assert.Equal(t, errors.Is(err, ErrInvalidTarget), true, "Error should be ErrInvalidTarget")
+}
+
 func Unmarshall(contentType string, content string) (*Person, error) {
 	request := httptest.NewRequest(http.MethodPost, "/persons", strings.NewReader(content))
 	request.Header.Add("content-type", contentType)
 
-	p, err := UnmarshalBody(&Person{}, request)
-	person := p.(*Person)
+	person := &Person{}
+	err := UnmarshalBody(person, request)
 
 	return person, err
 }
@@ -195,8 +241,8 @@ func UnmarshallUnexpectedBody(contentType string) (*Person, error) {
 	request := httptest.NewRequest(http.MethodPost, "/persons", strings.NewReader(unexpectedBody))
 	request.Header.Add("content-type", "application/json")
 
-	p, err := UnmarshalBody(&Person{}, request)
-	person := p.(*Person)
+	person := &Person{}
+	err := UnmarshalBody(person, request)
 
 	return person, err
 }
@@ -0,0 +1,42 @@
+package cable
+
+import (
+	"context"
+	"net/http"
+	"sync"
+)
+
+type requestScopeKey struct{}
+
+// requestScope carries values from filters to the request handler
+type requestScope struct {
+	principal *Principal
+	csrfToken string
+	csrfField string
+	cspNonce  string
+	bodyLimit *BodyLimit
+	// bodyPrepared is set once the body is decoded and limited
+	bodyPrepared bool
+	clientIP     string
+	scheme       string
+	host         string
+	cacheTags    []string
+	session      *Session
+	// attributes may be accessed by request handlers running in their own goroutine
+	mutex      sync.Mutex
+	attributes map[interface{}]interface{}
+}
+
+func withRequestScope(request *http.Request) *http.Request {
+	ctx := context.WithValue(request.Context(), requestScopeKey{}, &requestScope{})
+	return request.WithContext(ctx)
+}
+
+func scopeOf(request *http.Request) *requestScope {
+	if scope, ok := request.Context().Value(requestScopeKey{}).(*requestScope); ok {
+		return scope
+	}
+
+	// the request is not handled by cable, values set on this scope are lost
+	return &requestScope{}
+}
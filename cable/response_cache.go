@@ -0,0 +1,383 @@
+package cable
+
+import (
+	"container/list"
+	"context"
+	"net/http"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	ageHeader    = "Age"
+	cookieHeader = "Cookie"
+)
+
+// CachedResponse is a response stored by ResponseCache
+type CachedResponse struct {
+	Status int
+	Header map[string]string
+	Body   []byte
+	// Vary lists the request headers the response depends on
+	Vary []string
+	// Tags are used to invalidate the response, they include the name of its route
+	Tags   []string
+	Stored time.Time
+	// Expires is the end of freshness, the response is served until StaleUntil while it is refreshed
+	Expires    time.Time
+	StaleUntil time.Time
+}
+
+// ResponseCacheStore keeps cached responses. Implementations must be safe for concurrent use.
+type ResponseCacheStore interface {
+	// Get returns the response if it has not been evicted and now is before StaleUntil
+	Get(key string, now time.Time) (CachedResponse, bool, error)
+	Set(key string, response CachedResponse) error
+	// Invalidate removes all responses tagged with tag
+	Invalidate(tag string) error
+}
+
+// ResponseCache stores responses to GET requests on the server. Requests with
+// credentials only use responses marked public, s-maxage or must-revalidate.
+type ResponseCache struct {
+	Store ResponseCacheStore
+	// TTL applies to responses without max-age or s-maxage directive
+	TTL        time.Duration
+	clock      func() time.Time
+	mutex      sync.Mutex
+	refreshing map[string]bool
+}
+
+// NewResponseCache keeps up to maxEntries responses in memory, evicting the least
+// recently used ones, and caches responses without Cache-Control header for ttl.
+// Register cable.Sessions before the cache, so responses using the session are not cached.
+func NewResponseCache(maxEntries int, ttl time.Duration) *ResponseCache {
+	return &ResponseCache{
+		Store:      NewMemoryResponseCacheStore(maxEntries),
+		TTL:        ttl,
+		clock:      time.Now,
+		refreshing: map[string]bool{}}
+}
+
+// CacheTags tags the response to request, so it can be invalidated with ResponseCache.Invalidate
+func CacheTags(request *http.Request, tags ...string) {
+	scope := scopeOf(request)
+	scope.cacheTags = append(scope.cacheTags, tags...)
+}
+
+// Invalidate removes responses of routes with the given names or tagged with the given tags
+func (c *ResponseCache) Invalidate(tags ...string) {
+	for _, tag := range tags {
+		if err := c.Store.Invalidate(tag); err != nil {
+			logger.Error("Invalidating cached responses failed",
+				zap.String("Tag", tag),
+				zap.Error(err))
+		}
+	}
+}
+
+// Route returns an interceptor caching the responses of a route under name. Register it with
+// cable.Intercept(pattern, cache.Route(name)).
+func (c *ResponseCache) Route(name string) func(requestEntity RequestEntity, response *ResponseEntity, next func(requestEntity RequestEntity, response *ResponseEntity)) {
+	return func(requestEntity RequestEntity, response *ResponseEntity, next func(requestEntity RequestEntity, response *ResponseEntity)) {
+		request := requestEntity.Request
+
+		// responses depending on the session of a client are never shared
+		if request.Method != http.MethodGet || sessionDependent(request) {
+			next(requestEntity, response)
+			return
+		}
+
+		now := c.clock()
+		primaryKey := request.Method + " " + request.URL.Path + "?" + request.URL.Query().Encode()
+
+		if !hasDirective(request.Header.Get(cacheControlHeader), "no-cache") {
+			// responses are only shared with clients sending credentials if explicitly allowed
+			if cached, ok := c.lookup(primaryKey, request, now); ok && (!credentialed(requestEntity) || shareable(cached.Header[cacheControlHeader])) {
+				c.serve(cached, response, now)
+
+				if now.After(cached.Expires) {
+					c.refresh(primaryKey, variantKey(primaryKey, cached.Vary, request), name, requestEntity, next)
+				}
+
+				return
+			}
+		}
+
+		next(requestEntity, response)
+		c.store(primaryKey, name, requestEntity, response, now)
+	}
+}
+
+// lookup finds the variant of a response matching the headers of request
+func (c *ResponseCache) lookup(primaryKey string, request *http.Request, now time.Time) (CachedResponse, bool) {
+	primary, ok, err := c.Store.Get(primaryKey, now)
+
+	if err != nil {
+		logger.Error("Reading cached response failed", zap.Error(err))
+		return CachedResponse{}, false
+	}
+
+	if !ok || len(primary.Vary) == 0 {
+		return primary, ok
+	}
+
+	variant, ok, err := c.Store.Get(variantKey(primaryKey, primary.Vary, request), now)
+
+	if err != nil {
+		logger.Error("Reading cached response failed", zap.Error(err))
+		return CachedResponse{}, false
+	}
+
+	return variant, ok
+}
+
+func (c *ResponseCache) serve(cached CachedResponse, response *ResponseEntity, now time.Time) {
+	served := copyResponseEntity(ResponseEntity{Request: response.Request, Status: cached.Status, Header: cached.Header, Body: cached.Body})
+	served.Header[ageHeader] = strconv.FormatInt(int64(now.Sub(cached.Stored)/time.Second), 10)
+	*response = served
+}
+
+// refresh handles a request in the background while a stale response is served
+func (c *ResponseCache) refresh(primaryKey string, key string, name string, requestEntity RequestEntity, next func(requestEntity RequestEntity, response *ResponseEntity)) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.refreshing[key] {
+		return
+	}
+
+	c.refreshing[key] = true
+
+	// the response has been sent by the time the refresh completes, keep values but not cancellation
+	requestEntity.Request = requestEntity.Request.WithContext(context.WithoutCancel(requestEntity.Request.Context()))
+
+	go func() {
+		defer func() {
+			c.mutex.Lock()
+			delete(c.refreshing, key)
+			c.mutex.Unlock()
+		}()
+
+		response := ResponseEntity{Request: requestEntity.Request}
+		next(requestEntity, &response)
+		c.store(primaryKey, name, requestEntity, &response, c.clock())
+	}()
+}
+
+func (c *ResponseCache) store(primaryKey string, name string, requestEntity RequestEntity, response *ResponseEntity, now time.Time) {
+	// cookies and sessions are specific to the client
+	if response.Status != http.StatusOK || len(response.Cookies) > 0 || sessionDependent(requestEntity.Request) {
+		return
+	}
+
+	cacheControl := response.Header[cacheControlHeader]
+
+	if hasDirective(cacheControl, "no-store") || hasDirective(cacheControl, "no-cache") || hasDirective(cacheControl, "private") {
+		return
+	}
+
+	// responses to authenticated requests are only shared if explicitly allowed
+	if credentialed(requestEntity) && !shareable(cacheControl) {
+		return
+	}
+
+	vary := []string{}
+
+	for _, header := range strings.Split(response.Header[varyHeader], ",") {
+		if header = strings.TrimSpace(header); len(header) > 0 {
+			vary = append(vary, textproto.CanonicalMIMEHeaderKey(header))
+		}
+	}
+
+	for _, header := range vary {
+		if header == "*" {
+			return
+		}
+	}
+
+	ttl := c.TTL
+
+	if maxAge, ok := directiveSeconds(cacheControl, "max-age"); ok {
+		ttl = maxAge
+	}
+
+	if sharedMaxAge, ok := directiveSeconds(cacheControl, "s-maxage"); ok {
+		ttl = sharedMaxAge
+	}
+
+	if ttl <= 0 {
+		return
+	}
+
+	staleWhileRevalidate, _ := directiveSeconds(cacheControl, "stale-while-revalidate")
+	stored := copyResponseEntity(*response)
+	cached := CachedResponse{
+		Status:     stored.Status,
+		Header:     stored.Header,
+		Body:       stored.Body,
+		Vary:       vary,
+		Tags:       append([]string{name}, scopeOf(requestEntity.Request).cacheTags...),
+		Stored:     now,
+		Expires:    now.Add(ttl),
+		StaleUntil: now.Add(ttl + staleWhileRevalidate)}
+
+	key := primaryKey
+
+	if len(vary) > 0 {
+		// the primary entry only remembers which headers select the variant
+		if err := c.Store.Set(primaryKey, CachedResponse{Vary: vary, Tags: cached.Tags, Stored: now, Expires: cached.Expires, StaleUntil: cached.StaleUntil}); err != nil {
+			logger.Error("Storing cached response failed", zap.Error(err))
+			return
+		}
+
+		key = variantKey(primaryKey, vary, requestEntity.Request)
+	}
+
+	if err := c.Store.Set(key, cached); err != nil {
+		logger.Error("Storing cached response failed", zap.Error(err))
+	}
+}
+
+// credentialed returns true if the response may depend on credentials of the client
+func credentialed(requestEntity RequestEntity) bool {
+	request := requestEntity.Request
+
+	return requestEntity.Principal != nil || len(request.Header.Get(authorizationHeader)) > 0 || len(request.Header.Values(cookieHeader)) > 0
+}
+
+/*
+* A shared cache MUST NOT use a cached response to a request with an Authorization
+* header field to satisfy any subsequent request unless the response contains a
+* Cache-Control field with a response directive that allows it to be stored by a
+* shared cache, e.g. must-revalidate, public or s-maxage.
+*
+* https://www.rfc-editor.org/rfc/rfc9111#section-3.5
+ */
+func shareable(cacheControl string) bool {
+	return hasDirective(cacheControl, "public") || hasDirective(cacheControl, "s-maxage") || hasDirective(cacheControl, "must-revalidate")
+}
+
+func variantKey(primaryKey string, vary []string, request *http.Request) string {
+	key := primaryKey
+
+	for _, header := range vary {
+		key += "\n" + header + ": " + strings.Join(request.Header.Values(header), ",")
+	}
+
+	return key
+}
+
+func hasDirective(cacheControl string, directive string) bool {
+	for _, element := range strings.Split(cacheControl, ",") {
+		name := strings.SplitN(strings.TrimSpace(element), "=", 2)[0]
+
+		if strings.EqualFold(name, directive) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func directiveSeconds(cacheControl string, directive string) (time.Duration, bool) {
+	for _, element := range strings.Split(cacheControl, ",") {
+		kv := strings.SplitN(strings.TrimSpace(element), "=", 2)
+
+		if len(kv) == 2 && strings.EqualFold(kv[0], directive) {
+			if seconds, err := strconv.ParseInt(strings.Trim(kv[1], "\""), 10, 64); err == nil {
+				return time.Duration(seconds) * time.Second, true
+			}
+		}
+	}
+
+	return 0, false
+}
+
+type memoryCacheEntry struct {
+	key      string
+	response CachedResponse
+}
+
+type memoryResponseCacheStore struct {
+	mutex      sync.Mutex
+	maxEntries int
+	entries    map[string]*list.Element
+	// recent holds the entries, the most recently used first
+	recent *list.List
+}
+
+// NewMemoryResponseCacheStore keeps up to maxEntries responses of a single instance
+func NewMemoryResponseCacheStore(maxEntries int) ResponseCacheStore {
+	return &memoryResponseCacheStore{
+		maxEntries: maxEntries,
+		entries:    map[string]*list.Element{},
+		recent:     list.New()}
+}
+
+func (s *memoryResponseCacheStore) Get(key string, now time.Time) (CachedResponse, bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	element, ok := s.entries[key]
+
+	if !ok {
+		return CachedResponse{}, false, nil
+	}
+
+	entry := element.Value.(*memoryCacheEntry)
+
+	if !now.Before(entry.response.StaleUntil) {
+		s.remove(element)
+		return CachedResponse{}, false, nil
+	}
+
+	s.recent.MoveToFront(element)
+
+	return entry.response, true, nil
+}
+
+func (s *memoryResponseCacheStore) Set(key string, response CachedResponse) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if element, ok := s.entries[key]; ok {
+		element.Value.(*memoryCacheEntry).response = response
+		s.recent.MoveToFront(element)
+		return nil
+	}
+
+	s.entries[key] = s.recent.PushFront(&memoryCacheEntry{key: key, response: response})
+
+	for s.maxEntries > 0 && s.recent.Len() > s.maxEntries {
+		s.remove(s.recent.Back())
+	}
+
+	return nil
+}
+
+func (s *memoryResponseCacheStore) Invalidate(tag string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, element := range s.entries {
+		for _, t := range element.Value.(*memoryCacheEntry).response.Tags {
+			if t == tag {
+				s.remove(element)
+				break
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *memoryResponseCacheStore) remove(element *list.Element) {
+	delete(s.entries, element.Value.(*memoryCacheEntry).key)
+	s.recent.Remove(element)
+}
@@ -0,0 +1,250 @@
+package cable
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	assert "github.com/stfsy/golang-assert"
+)
+
+func TestResponseCacheServesCachedResponse(t *testing.T) {
+	reset()
+	cache, calls := registerCachedPersons("")
+
+	first := getCached("/persons", nil)
+	*cache.now = epoch.Add(30 * time.Second)
+	second := getCached("/persons", nil)
+
+	assert.Equal(t, first.Body.String(), "persons 1", "First response is handled")
+	assert.Equal(t, second.Body.String(), "persons 1", "Second response is cached")
+	assert.Equal(t, second.Header().Get("Age"), "30", "Age header is set")
+	assert.Equal(t, *calls, 1, "Handler is called once")
+}
+
+func TestResponseCacheKeyIncludesQuery(t *testing.T) {
+	reset()
+	_, calls := registerCachedPersons("")
+
+	getCached("/persons?page=1&size=10", nil)
+	getCached("/persons?size=10&page=1", nil)
+	getCached("/persons?page=2&size=10", nil)
+
+	assert.Equal(t, *calls, 2, "Handler is called per query")
+}
+
+func TestResponseCacheHonorsMaxAge(t *testing.T) {
+	reset()
+	cache, calls := registerCachedPersons("max-age=10")
+
+	getCached("/persons", nil)
+	*cache.now = epoch.Add(11 * time.Second)
+	recorder := getCached("/persons", nil)
+
+	assert.Equal(t, recorder.Body.String(), "persons 2", "Expired response is handled again")
+	assert.Equal(t, *calls, 2, "Handler is called twice")
+}
+
+func TestResponseCacheHonorsNoStore(t *testing.T) {
+	reset()
+	_, calls := registerCachedPersons("no-store")
+
+	getCached("/persons", nil)
+	getCached("/persons", nil)
+
+	assert.Equal(t, *calls, 2, "Handler is called twice")
+}
+
+func TestResponseCacheHonorsLowerCaseHeaders(t *testing.T) {
+	reset()
+	cache := newTestResponseCache()
+	calls := 0
+	Intercept("/persons", cache.Route("persons"))
+	Get("/persons", func(req RequestEntity, resp *ResponseEntity) {
+		calls++
+		resp.Status = 200
+		resp.Header = map[string]string{"cache-control": "no-store"}
+	})
+
+	getCached("/persons", nil)
+	getCached("/persons", nil)
+
+	assert.Equal(t, calls, 2, "Handler is called twice")
+}
+
+func TestResponseCacheVariesByHeader(t *testing.T) {
+	reset()
+	cache := newTestResponseCache()
+	calls := 0
+	Intercept("/persons", cache.Route("persons"))
+	Get("/persons", func(req RequestEntity, resp *ResponseEntity) {
+		calls++
+		resp.Status = 200
+		resp.SetHeader("Vary", "Accept-Language")
+		resp.Body = []byte(req.Request.Header.Get("Accept-Language"))
+	})
+
+	getCached("/persons", map[string]string{"Accept-Language": "de"})
+	english := getCached("/persons", map[string]string{"Accept-Language": "en"})
+	german := getCached("/persons", map[string]string{"Accept-Language": "de"})
+
+	assert.Equal(t, english.Body.String(), "en", "English variant is handled")
+	assert.Equal(t, german.Body.String(), "de", "German variant is cached")
+	assert.Equal(t, calls, 2, "Handler is called per variant")
+}
+
+func TestResponseCacheInvalidatesByRouteAndTag(t *testing.T) {
+	reset()
+	cache, calls := registerCachedPersons("")
+
+	getCached("/persons", nil)
+	cache.Invalidate("persons")
+	getCached("/persons", nil)
+	cache.Invalidate("tenant-1")
+	getCached("/persons", nil)
+	cache.Invalidate("tenant-2")
+	getCached("/persons", nil)
+
+	assert.Equal(t, *calls, 3, "Handler is called after each invalidation")
+}
+
+func TestResponseCacheStaleWhileRevalidate(t *testing.T) {
+	reset()
+	cache, calls := registerCachedPersons("max-age=10, stale-while-revalidate=60")
+
+	getCached("/persons", nil)
+	*cache.now = epoch.Add(20 * time.Second)
+	stale := getCached("/persons", nil)
+
+	for i := 0; i < 100 && cache.isRefreshing(); i++ {
+		time.Sleep(time.Millisecond)
+	}
+
+	fresh := getCached("/persons", nil)
+
+	assert.Equal(t, stale.Body.String(), "persons 1", "Stale response is served")
+	assert.Equal(t, fresh.Body.String(), "persons 2", "Refreshed response is served")
+	assert.Equal(t, *calls, 2, "Handler is called once in the background")
+}
+
+func TestResponseCacheDoesNotShareSessions(t *testing.T) {
+	reset()
+	cache := newTestResponseCache()
+	calls := 0
+	registerSessionLogin(NewCookieSessions(sessionKey))
+	Intercept("/persons", cache.Route("persons"))
+	Get("/persons", func(req RequestEntity, resp *ResponseEntity) {
+		calls++
+		user, _ := req.Session.Get("user").(string)
+		resp.Status = 200
+		resp.Body = []byte("Hello " + user)
+	})
+
+	anonymous := getWithSession(nil)
+	alice := getWithSession(login("alice"))
+	bob := getWithSession(login("bob"))
+	again := getWithSession(nil)
+
+	assert.Equal(t, anonymous.Body.String(), "Hello ", "Anonymous client gets its response")
+	assert.Equal(t, alice.Body.String(), "Hello alice", "Alice gets her response")
+	assert.Equal(t, bob.Body.String(), "Hello bob", "Bob gets his response")
+	assert.Equal(t, again.Body.String(), "Hello ", "Anonymous client gets its response")
+	assert.Equal(t, calls, 4, "Responses using the session are not cached")
+}
+
+func TestResponseCacheDoesNotShareResponsesToCredentials(t *testing.T) {
+	reset()
+	_, calls := registerCachedPersons("max-age=60")
+
+	getCached("/persons", map[string]string{"Authorization": "Bearer mario"})
+	anonymous := getCached("/persons", nil)
+	withCookie := getCached("/persons", map[string]string{"Cookie": "theme=dark"})
+
+	assert.Equal(t, anonymous.Body.String(), "persons 2", "Response to Authorization is not stored")
+	assert.Equal(t, withCookie.Body.String(), "persons 3", "Cached response is not served to Cookie")
+	assert.Equal(t, *calls, 3, "Handler is called three times")
+}
+
+func TestResponseCacheSharesPublicResponsesToCredentials(t *testing.T) {
+	reset()
+	_, calls := registerCachedPersons("public, max-age=60")
+
+	getCached("/persons", map[string]string{"Authorization": "Bearer mario"})
+	anonymous := getCached("/persons", nil)
+	withCookie := getCached("/persons", map[string]string{"Cookie": "theme=dark"})
+
+	assert.Equal(t, anonymous.Body.String(), "persons 1", "Public response is shared")
+	assert.Equal(t, withCookie.Body.String(), "persons 1", "Public response is served to Cookie")
+	assert.Equal(t, *calls, 1, "Handler is called once")
+}
+
+func TestMemoryResponseCacheStoreEvictsLeastRecentlyUsed(t *testing.T) {
+	store := NewMemoryResponseCacheStore(2)
+	response := CachedResponse{StaleUntil: epoch.Add(time.Hour)}
+
+	store.Set("a", response)
+	store.Set("b", response)
+	store.Get("a", epoch)
+	store.Set("c", response)
+
+	_, a, _ := store.Get("a", epoch)
+	_, b, _ := store.Get("b", epoch)
+
+	assert.Equal(t, a, true, "Recently used entry is kept")
+	assert.Equal(t, b, false, "Least recently used entry is evicted")
+}
+
+type testResponseCache struct {
+	*ResponseCache
+	now *time.Time
+}
+
+func newTestResponseCache() testResponseCache {
+	now := epoch
+	cache := NewResponseCache(100, time.Minute)
+	cache.clock = func() time.Time { return now }
+
+	return testResponseCache{cache, &now}
+}
+
+func (c testResponseCache) isRefreshing() bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return len(c.refreshing) > 0
+}
+
+func registerCachedPersons(cacheControl string) (testResponseCache, *int) {
+	cache := newTestResponseCache()
+	calls := 0
+	Intercept("/persons", cache.Route("persons"))
+	Get("/persons", func(req RequestEntity, resp *ResponseEntity) {
+		calls++
+		CacheTags(req.Request, "tenant-"+strconv.Itoa(calls))
+		resp.Status = 200
+		resp.Body = []byte("persons " + strconv.Itoa(calls))
+
+		if len(cacheControl) > 0 {
+			resp.SetHeader("Cache-Control", cacheControl)
+		}
+	})
+
+	return cache, &calls
+}
+
+func getCached(path string, header map[string]string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(http.MethodGet, path, strings.NewReader(""))
+
+	for key, value := range header {
+		request.Header.Set(key, value)
+	}
+
+	recorder := httptest.NewRecorder()
+
+	HandleRequest(recorder, request)
+
+	return recorder
+}
@@ -0,0 +1,42 @@
+package cable
+
+import (
+	"sort"
+)
+
+// Route describes a registered request handler
+type Route struct {
+	Method  string
+	Pattern string
+	// Authorization lists the requirements checked before the handler of the route is called
+	Authorization []string
+}
+
+// Routes lists all registered request handlers, e.g. to audit their authorization
+func Routes() []Route {
+	methods := []string{}
+
+	for method := range handlers {
+		methods = append(methods, method)
+	}
+
+	sort.Strings(methods)
+
+	routes := []Route{}
+
+	for _, method := range methods {
+		for _, handler := range handlers[method] {
+			route := Route{Method: method, Pattern: handler.pattern, Authorization: []string{}}
+
+			for _, authorization := range findAuthorizations(handler.pattern) {
+				for _, requirement := range authorization.Requirements {
+					route.Authorization = append(route.Authorization, requirement.String())
+				}
+			}
+
+			routes = append(routes, route)
+		}
+	}
+
+	return routes
+}
@@ -0,0 +1,85 @@
+package cable
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	assert "github.com/stfsy/golang-assert"
+)
+
+func TestSecurityHeadersDefaults(t *testing.T) {
+	reset()
+	Filter("/.*", DefaultSecurityHeaders().Filter)
+	registerGetPersons()
+
+	request := httptest.NewRequest(http.MethodGet, "/persons", strings.NewReader(""))
+	recorder := httptest.NewRecorder()
+
+	HandleRequest(recorder, request)
+
+	header := recorder.Header()
+
+	assert.Equal(t, header.Get("X-Content-Type-Options"), "nosniff", "X-Content-Type-Options is nosniff")
+	assert.Equal(t, header.Get("X-Frame-Options"), "DENY", "X-Frame-Options is DENY")
+	assert.Equal(t, header.Get("Referrer-Policy"), "strict-origin-when-cross-origin", "Referrer-Policy is set")
+	assert.Equal(t, header.Get("Cross-Origin-Opener-Policy"), "same-origin", "Cross-Origin-Opener-Policy is set")
+	assert.Equal(t, header.Get("Strict-Transport-Security"), "", "HSTS is not sent over http")
+	assert.Equal(t, len(header["Cross-Origin-Embedder-Policy"]), 0, "Cross-Origin-Embedder-Policy is not set")
+}
+
+func TestSecurityHeadersHSTSOverHTTPS(t *testing.T) {
+	reset()
+	Filter("/.*", DefaultSecurityHeaders().Filter)
+	registerGetPersons()
+
+	request := httptest.NewRequest(http.MethodGet, "https://example.com/persons", strings.NewReader(""))
+	request.TLS = &tls.ConnectionState{}
+	recorder := httptest.NewRecorder()
+
+	HandleRequest(recorder, request)
+
+	assert.Equal(t, recorder.Header().Get("Strict-Transport-Security"), "max-age=63072000; includeSubDomains", "HSTS is sent over https")
+}
+
+func TestSecurityHeadersNonceIsExposedToHandler(t *testing.T) {
+	reset()
+	Filter("/.*", DefaultSecurityHeaders().Filter)
+	Get("/page", func(req RequestEntity, resp *ResponseEntity) {
+		resp.Status = 200
+		resp.Body = []byte(CSPNonce(req.Request))
+	})
+
+	request := httptest.NewRequest(http.MethodGet, "/page", strings.NewReader(""))
+	recorder := httptest.NewRecorder()
+
+	HandleRequest(recorder, request)
+
+	nonce := recorder.Body.String()
+
+	assert.NotEqual(t, nonce, "", "Nonce is not empty")
+	assert.Equal(t, strings.Contains(recorder.Header().Get("Content-Security-Policy"), "'nonce-"+nonce+"'"), true, "Policy contains the nonce")
+}
+
+func TestSecurityHeadersRouteOverride(t *testing.T) {
+	reset()
+	Filter("/.*", DefaultSecurityHeaders().Filter)
+	embeddable := DefaultSecurityHeaders()
+	embeddable.FrameOptions = ""
+	embeddable.ContentSecurityPolicy = "frame-ancestors https://partner.example; script-src 'nonce-{nonce}'"
+	Filter("/embed", embeddable.Filter)
+	Get("/embed", func(req RequestEntity, resp *ResponseEntity) {
+		resp.Status = 200
+		resp.Body = []byte(CSPNonce(req.Request))
+	})
+
+	request := httptest.NewRequest(http.MethodGet, "/embed", strings.NewReader(""))
+	recorder := httptest.NewRecorder()
+
+	HandleRequest(recorder, request)
+
+	assert.Equal(t, len(recorder.Header()["X-Frame-Options"]), 0, "X-Frame-Options is removed")
+	assert.Equal(t, recorder.Header().Get("Content-Security-Policy"), "frame-ancestors https://partner.example; script-src 'nonce-"+recorder.Body.String()+"'", "Policy is overridden and keeps the nonce")
+}
@@ -0,0 +1,583 @@
+package cable
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	sessionIDLength = 32
+	maxCookieSize   = 4096
+)
+
+// Session holds values of a client across requests. Values are encoded as JSON. The
+// session is loaded when it is first used by the request handler and only saved if it
+// existed before or was modified, so requests not using it create no session.
+// Changes after the request handler returned, e.g. by handlers still running after a
+// Timeout, are discarded.
+type Session struct {
+	mutex    sync.Mutex
+	id       string
+	values   map[string]interface{}
+	created  time.Time
+	accessed time.Time
+	flashes  []string
+	// previousID is deleted from the store once the session is saved
+	previousID string
+	destroyed  bool
+	// presented is set if the request carried a session cookie
+	presented bool
+	// loaded is set once the session is used, existing if it was sent by the client
+	loaded   bool
+	existing bool
+	modified bool
+	// finished is set once the session is saved, later changes are discarded
+	finished bool
+	load     func(session *Session)
+}
+
+// sessionData is the encoded form of a session
+type sessionData struct {
+	ID       string                 `json:"id"`
+	Values   map[string]interface{} `json:"values,omitempty"`
+	Flashes  []string               `json:"flashes,omitempty"`
+	Created  int64                  `json:"created"`
+	Accessed int64                  `json:"accessed"`
+}
+
+func (s *Session) ID() string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.use()
+	return s.id
+}
+
+func (s *Session) Get(key string) interface{} {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.use()
+	return s.values[key]
+}
+
+func (s *Session) Set(key string, value interface{}) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.modify() {
+		s.values[key] = value
+	}
+}
+
+func (s *Session) Delete(key string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.modify() {
+		delete(s.values, key)
+	}
+}
+
+// AddFlash stores a message to be shown once, e.g. after a redirect
+func (s *Session) AddFlash(message string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.modify() {
+		s.flashes = append(s.flashes, message)
+	}
+}
+
+// Flashes returns and removes all flash messages
+func (s *Session) Flashes() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.use()
+	flashes := s.flashes
+
+	if !s.finished {
+		s.flashes = nil
+	}
+
+	return flashes
+}
+
+// Regenerate changes the session id while keeping its values. Call it on login and
+// other privilege changes to prevent session fixation.
+func (s *Session) Regenerate() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if !s.modify() {
+		return
+	}
+
+	if len(s.previousID) == 0 {
+		s.previousID = s.id
+	}
+
+	s.id = newSessionID()
+}
+
+// Destroy removes the session, e.g. on logout
+func (s *Session) Destroy() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.modify() {
+		s.destroyed = true
+	}
+}
+
+// use loads the session on first use, callers must hold the mutex
+func (s *Session) use() {
+	if !s.loaded && !s.finished {
+		s.loaded = true
+		s.load(s)
+	}
+}
+
+// modify returns false if the session has already been saved
+func (s *Session) modify() bool {
+	if s.finished {
+		return false
+	}
+
+	s.use()
+	s.modified = true
+
+	return true
+}
+
+// finish discards all following changes and returns true if the session must be saved
+func (s *Session) finish() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.finished = true
+
+	return s.existing || s.modified
+}
+
+// dependent returns true if the session was sent by the client or used by the handler
+func (s *Session) dependent() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.presented || s.loaded
+}
+
+func (s *Session) data() sessionData {
+	return sessionData{
+		ID:       s.id,
+		Values:   s.values,
+		Flashes:  s.flashes,
+		Created:  s.created.Unix(),
+		Accessed: s.accessed.Unix()}
+}
+
+// SessionStore keeps encoded sessions on the server. Implementations must be safe for concurrent use.
+type SessionStore interface {
+	Load(id string, now time.Time) ([]byte, bool, error)
+	Save(id string, data []byte, expires time.Time) error
+	Delete(id string) error
+}
+
+// Sessions loads the session of a request before the request handler runs and saves
+// it afterwards. Sessions are either kept in a cookie or in a SessionStore, in which
+// case the cookie only carries the signed session id.
+type Sessions struct {
+	CookieName string
+	// Keys sign cookies with the first key and verify them with all keys, so keys can be rotated
+	Keys [][]byte
+	// Encrypt cookies with AES-GCM, so clients cannot read the values
+	Encrypt bool
+	// Store keeps sessions on the server if set
+	Store SessionStore
+	// IdleTimeout ends sessions not used for this long
+	IdleTimeout time.Duration
+	// AbsoluteTimeout ends sessions this long after they were created
+	AbsoluteTimeout time.Duration
+	// Secure limits the cookie to https
+	Secure bool
+	clock  func() time.Time
+}
+
+// NewCookieSessions keeps sessions in signed cookies. Register it with
+// cable.Intercept(pattern, sessions.Intercept).
+func NewCookieSessions(keys ...[]byte) *Sessions {
+	return &Sessions{
+		CookieName:      "session",
+		Keys:            keys,
+		IdleTimeout:     30 * time.Minute,
+		AbsoluteTimeout: 12 * time.Hour,
+		Secure:          true,
+		clock:           time.Now}
+}
+
+// NewStoreSessions keeps sessions in store and session ids in signed cookies
+func NewStoreSessions(store SessionStore, keys ...[]byte) *Sessions {
+	sessions := NewCookieSessions(keys...)
+	sessions.Store = store
+
+	return sessions
+}
+
+func (s *Sessions) Intercept(requestEntity RequestEntity, response *ResponseEntity, next func(requestEntity RequestEntity, response *ResponseEntity)) {
+	request := requestEntity.Request
+	now := s.clock()
+	_, err := request.Cookie(s.CookieName)
+
+	session := &Session{presented: err == nil}
+	session.load = func(session *Session) {
+		if !s.load(session, request, now) {
+			session.id = newSessionID()
+			session.values = map[string]interface{}{}
+			session.created = now
+		}
+
+		session.accessed = now
+	}
+
+	requestEntity.Session = session
+	scopeOf(request).session = session
+
+	next(requestEntity, response)
+
+	if !session.finish() {
+		return
+	}
+
+	if err := s.save(session, response, now); err != nil {
+		logger.Error("Saving session failed",
+			zap.String("Path", request.URL.Path),
+			zap.Error(err))
+	}
+}
+
+// sessionDependent reports whether the request carried a session cookie or used its
+// session, so the response depends on the client and must not be shared with others
+func sessionDependent(request *http.Request) bool {
+	session := scopeOf(request).session
+
+	return session != nil && session.dependent()
+}
+
+// load reads the session sent by the client into session
+func (s *Sessions) load(session *Session, request *http.Request, now time.Time) bool {
+	cookie, err := request.Cookie(s.CookieName)
+
+	if err != nil {
+		return false
+	}
+
+	payload, ok := s.decode(cookie.Value)
+
+	if !ok {
+		logger.Info("Session cookie is invalid",
+			zap.String("Path", request.URL.Path))
+		return false
+	}
+
+	if s.Store != nil {
+		payload, ok, err = s.Store.Load(string(payload), now)
+
+		if err != nil {
+			logger.Error("Loading session failed", zap.Error(err))
+			return false
+		}
+
+		if !ok {
+			return false
+		}
+	}
+
+	data := sessionData{}
+
+	if err := json.Unmarshal(payload, &data); err != nil {
+		return false
+	}
+
+	created, accessed := time.Unix(data.Created, 0), time.Unix(data.Accessed, 0)
+
+	if s.expired(created, accessed, now) {
+		if s.Store != nil {
+			s.Store.Delete(data.ID)
+		}
+
+		return false
+	}
+
+	session.id = data.ID
+	session.values = data.Values
+	session.created = created
+	session.flashes = data.Flashes
+	session.existing = true
+
+	if session.values == nil {
+		session.values = map[string]interface{}{}
+	}
+
+	return true
+}
+
+func (s *Sessions) expired(created time.Time, accessed time.Time, now time.Time) bool {
+	if s.IdleTimeout > 0 && now.Sub(accessed) > s.IdleTimeout {
+		return true
+	}
+
+	return s.AbsoluteTimeout > 0 && now.Sub(created) > s.AbsoluteTimeout
+}
+
+func (s *Sessions) save(session *Session, response *ResponseEntity, now time.Time) error {
+	if s.Store != nil && len(session.previousID) > 0 {
+		if err := s.Store.Delete(session.previousID); err != nil {
+			return err
+		}
+	}
+
+	if session.destroyed {
+		if s.Store != nil {
+			if err := s.Store.Delete(session.id); err != nil {
+				return err
+			}
+		}
+
+		response.SetCookie(s.cookie("", -1))
+		return nil
+	}
+
+	payload, err := json.Marshal(session.data())
+
+	if err != nil {
+		return err
+	}
+
+	if s.Store != nil {
+		if err := s.Store.Save(session.id, payload, s.expires(session, now)); err != nil {
+			return err
+		}
+
+		payload = []byte(session.id)
+	}
+
+	value, err := s.encode(payload)
+
+	if err != nil {
+		return err
+	}
+
+	if len(value) > maxCookieSize {
+		return errors.New("session cookie exceeds 4096 bytes, use a SessionStore")
+	}
+
+	maxAge := 0
+
+	// a zero max age would keep the cookie until the browser is closed
+	if expires := s.expires(session, now); !expires.IsZero() {
+		maxAge = int(expires.Sub(now) / time.Second)
+
+		if maxAge <= 0 {
+			maxAge = -1
+		}
+	}
+
+	response.SetCookie(s.cookie(value, maxAge))
+
+	return nil
+}
+
+// expires is zero if sessions do not expire
+func (s *Sessions) expires(session *Session, now time.Time) time.Time {
+	expires := time.Time{}
+
+	if s.AbsoluteTimeout > 0 {
+		expires = session.created.Add(s.AbsoluteTimeout)
+	}
+
+	if s.IdleTimeout > 0 && (expires.IsZero() || now.Add(s.IdleTimeout).Before(expires)) {
+		expires = now.Add(s.IdleTimeout)
+	}
+
+	return expires
+}
+
+func (s *Sessions) cookie(value string, maxAge int) *http.Cookie {
+	return &http.Cookie{
+		Name:     s.CookieName,
+		Value:    value,
+		Path:     "/",
+		MaxAge:   maxAge,
+		Secure:   s.Secure,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode}
+}
+
+// encode signs and optionally encrypts payload with the first key
+func (s *Sessions) encode(payload []byte) (string, error) {
+	if len(s.Keys) == 0 {
+		return "", errors.New("sessions have no key")
+	}
+
+	if s.Encrypt {
+		aead, err := sessionCipher(s.Keys[0])
+
+		if err != nil {
+			return "", err
+		}
+
+		nonce := make([]byte, aead.NonceSize())
+
+		if _, err := rand.Read(nonce); err != nil {
+			return "", err
+		}
+
+		payload = aead.Seal(nonce, nonce, payload, []byte(s.CookieName))
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+
+	return encoded + "." + signSession(s.Keys[0], encoded), nil
+}
+
+// decode verifies and decrypts a cookie value with any key
+func (s *Sessions) decode(value string) ([]byte, bool) {
+	parts := strings.SplitN(value, ".", 2)
+
+	if len(parts) != 2 {
+		return nil, false
+	}
+
+	for _, key := range s.Keys {
+		if !hmac.Equal([]byte(parts[1]), []byte(signSession(key, parts[0]))) {
+			continue
+		}
+
+		payload, err := base64.RawURLEncoding.DecodeString(parts[0])
+
+		if err != nil || !s.Encrypt {
+			return payload, err == nil
+		}
+
+		aead, err := sessionCipher(key)
+
+		if err != nil || len(payload) < aead.NonceSize() {
+			return nil, false
+		}
+
+		payload, err = aead.Open(nil, payload[:aead.NonceSize()], payload[aead.NonceSize():], []byte(s.CookieName))
+
+		return payload, err == nil
+	}
+
+	return nil, false
+}
+
+func signSession(key []byte, value string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(value))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// sessionCipher derives the encryption key, so the signing key is not reused for encryption
+func sessionCipher(key []byte) (cipher.AEAD, error) {
+	derived := hmac.New(sha256.New, key)
+	derived.Write([]byte("session encryption"))
+
+	block, err := aes.NewCipher(derived.Sum(nil))
+
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+func newSessionID() string {
+	id := make([]byte, sessionIDLength)
+	rand.Read(id)
+
+	return base64.RawURLEncoding.EncodeToString(id)
+}
+
+type memorySession struct {
+	data    []byte
+	expires time.Time
+}
+
+func (m memorySession) expired(now time.Time) bool {
+	return !m.expires.IsZero() && !now.Before(m.expires)
+}
+
+type memorySessionStore struct {
+	mutex     sync.Mutex
+	sessions  map[string]memorySession
+	lastSweep time.Time
+}
+
+// NewMemorySessionStore keeps sessions of a single instance
+func NewMemorySessionStore() SessionStore {
+	return &memorySessionStore{sessions: map[string]memorySession{}}
+}
+
+func (s *memorySessionStore) Load(id string, now time.Time) ([]byte, bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.sweep(now)
+
+	session, ok := s.sessions[id]
+
+	if !ok || session.expired(now) {
+		return nil, false, nil
+	}
+
+	return session.data, true, nil
+}
+
+func (s *memorySessionStore) Save(id string, data []byte, expires time.Time) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.sessions[id] = memorySession{data: data, expires: expires}
+
+	return nil
+}
+
+func (s *memorySessionStore) Delete(id string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.sessions, id)
+
+	return nil
+}
+
+func (s *memorySessionStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < time.Minute {
+		return
+	}
+
+	for id, session := range s.sessions {
+		if session.expired(now) {
+			delete(s.sessions, id)
+		}
+	}
+
+	s.lastSweep = now
+}
@@ -0,0 +1,267 @@
+package cable
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	assert "github.com/stfsy/golang-assert"
+)
+
+var sessionKey = []byte("session key")
+
+func TestCookieSessionKeepsValues(t *testing.T) {
+	reset()
+	registerSessionCounter(NewCookieSessions(sessionKey))
+
+	first := getWithSession(nil)
+	second := getWithSession(sessionCookie(first))
+
+	assert.Equal(t, first.Body.String(), "1", "First request starts the session")
+	assert.Equal(t, second.Body.String(), "2", "Second request continues the session")
+	assert.Equal(t, sessionCookie(first).HttpOnly, true, "Cookie is http only")
+}
+
+func TestCookieSessionRejectsTamperedCookie(t *testing.T) {
+	reset()
+	registerSessionCounter(NewCookieSessions(sessionKey))
+
+	cookie := sessionCookie(getWithSession(nil))
+	cookie.Value = "x" + cookie.Value
+	recorder := getWithSession(cookie)
+
+	assert.Equal(t, recorder.Body.String(), "1", "A new session is started")
+}
+
+func TestCookieSessionIsEncrypted(t *testing.T) {
+	reset()
+	sessions := NewCookieSessions(sessionKey)
+	sessions.Encrypt = true
+	registerSessionCounter(sessions)
+
+	first := getWithSession(nil)
+	second := getWithSession(sessionCookie(first))
+
+	assert.Equal(t, strings.Contains(sessionCookie(first).Value, "eyJ"), false, "Cookie does not contain JSON")
+	assert.Equal(t, second.Body.String(), "2", "Second request continues the session")
+}
+
+func TestCookieSessionKeyRotation(t *testing.T) {
+	reset()
+	registerSessionCounter(NewCookieSessions(sessionKey))
+	cookie := sessionCookie(getWithSession(nil))
+
+	reset()
+	registerSessionCounter(NewCookieSessions([]byte("new key"), sessionKey))
+	rotated := getWithSession(cookie)
+
+	reset()
+	registerSessionCounter(NewCookieSessions([]byte("new key")))
+	dropped := getWithSession(cookie)
+
+	assert.Equal(t, rotated.Body.String(), "2", "Cookies signed with old keys are accepted")
+	assert.Equal(t, dropped.Body.String(), "1", "Cookies signed with removed keys are rejected")
+}
+
+func TestSessionIdleExpiry(t *testing.T) {
+	reset()
+	now := epoch
+	sessions := NewCookieSessions(sessionKey)
+	sessions.clock = func() time.Time { return now }
+	registerSessionCounter(sessions)
+
+	cookie := sessionCookie(getWithSession(nil))
+	now = now.Add(29 * time.Minute)
+	active := getWithSession(cookie)
+	now = now.Add(31 * time.Minute)
+	idle := getWithSession(sessionCookie(active))
+
+	assert.Equal(t, active.Body.String(), "2", "Active session continues")
+	assert.Equal(t, idle.Body.String(), "1", "Idle session ends")
+}
+
+func TestSessionAbsoluteExpiry(t *testing.T) {
+	reset()
+	now := epoch
+	sessions := NewCookieSessions(sessionKey)
+	sessions.AbsoluteTimeout = time.Hour
+	sessions.clock = func() time.Time { return now }
+	registerSessionCounter(sessions)
+
+	recorder := getWithSession(nil)
+
+	for i := 0; i < 4; i++ {
+		now = now.Add(20 * time.Minute)
+		recorder = getWithSession(sessionCookie(recorder))
+	}
+
+	assert.Equal(t, recorder.Body.String(), "1", "Session ends an hour after it was created")
+}
+
+func TestStoreSessionRegenerate(t *testing.T) {
+	reset()
+	store := NewMemorySessionStore()
+	sessions := NewStoreSessions(store, sessionKey)
+	Intercept("/.*", sessions.Intercept)
+	Post("/login", func(req RequestEntity, resp *ResponseEntity) {
+		req.Session.Regenerate()
+		req.Session.Set("user", "mario")
+		req.Session.AddFlash("Welcome back")
+		resp.Status = 200
+	})
+	Get("/persons", func(req RequestEntity, resp *ResponseEntity) {
+		user, _ := req.Session.Get("user").(string)
+		resp.Status = 200
+		resp.Body = []byte(user + " " + strings.Join(req.Session.Flashes(), ","))
+	})
+	Get("/cart", func(req RequestEntity, resp *ResponseEntity) {
+		req.Session.Set("cart", "empty")
+		resp.Status = 200
+	})
+
+	cart := httptest.NewRecorder()
+	HandleRequest(cart, httptest.NewRequest(http.MethodGet, "/cart", strings.NewReader("")))
+	anonymous := sessionCookie(cart)
+	request := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(""))
+	request.AddCookie(anonymous)
+	login := httptest.NewRecorder()
+	HandleRequest(login, request)
+
+	first := getWithSession(sessionCookie(login))
+	second := getWithSession(sessionCookie(first))
+	fixated := getWithSession(anonymous)
+
+	assert.NotEqual(t, sessionCookie(login).Value, anonymous.Value, "Session id changes on login")
+	assert.Equal(t, first.Body.String(), "mario Welcome back", "Values and flashes are kept")
+	assert.Equal(t, second.Body.String(), "mario ", "Flashes are shown once")
+	assert.Equal(t, fixated.Body.String(), " ", "Previous session id is invalid")
+}
+
+func TestSessionDestroy(t *testing.T) {
+	reset()
+	Intercept("/.*", NewStoreSessions(NewMemorySessionStore(), sessionKey).Intercept)
+	Get("/persons", func(req RequestEntity, resp *ResponseEntity) {
+		req.Session.Destroy()
+		resp.Status = 200
+	})
+
+	recorder := getWithSession(nil)
+
+	assert.Equal(t, sessionCookie(recorder).MaxAge, -1, "Cookie is deleted")
+}
+
+func TestUnusedSessionIsNotSaved(t *testing.T) {
+	reset()
+	store := NewMemorySessionStore()
+	Intercept("/.*", NewStoreSessions(store, sessionKey).Intercept)
+	Get("/persons", func(req RequestEntity, resp *ResponseEntity) {
+		resp.Status = 200
+	})
+	Get("/greeting", func(req RequestEntity, resp *ResponseEntity) {
+		user, _ := req.Session.Get("user").(string)
+		resp.Status = 200
+		resp.Body = []byte("Hello " + user)
+	})
+
+	unused := getWithSession(nil)
+	read := httptest.NewRecorder()
+	HandleRequest(read, httptest.NewRequest(http.MethodGet, "/greeting", strings.NewReader("")))
+
+	assert.Equal(t, sessionCookie(unused), (*http.Cookie)(nil), "No cookie is set if the session is not used")
+	assert.Equal(t, sessionCookie(read), (*http.Cookie)(nil), "No cookie is set if a new session is only read")
+	assert.Equal(t, len(store.(*memorySessionStore).sessions), 0, "No session is stored")
+}
+
+func TestSessionDiscardsChangesAfterTimeout(t *testing.T) {
+	reset()
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+	Intercept("/.*", NewStoreSessions(NewMemorySessionStore(), sessionKey).Intercept)
+	Intercept("/persons", Timeout(10*time.Millisecond, http.StatusServiceUnavailable))
+	Get("/persons", func(req RequestEntity, resp *ResponseEntity) {
+		defer close(stopped)
+		req.Session.Set("user", "mario")
+		<-req.Request.Context().Done()
+
+		// keep writing while the session is saved and after the response is sent
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				return
+			default:
+				req.Session.Set("late", i)
+				req.Session.AddFlash("late")
+			}
+		}
+	})
+	Get("/greeting", func(req RequestEntity, resp *ResponseEntity) {
+		user, _ := req.Session.Get("user").(string)
+		resp.Status = 200
+		resp.Body = []byte(user + " " + strconv.FormatBool(req.Session.Get("late") != nil))
+	})
+
+	timedOut := getWithSession(nil)
+	close(stop)
+	<-stopped
+
+	request := httptest.NewRequest(http.MethodGet, "/greeting", strings.NewReader(""))
+	request.AddCookie(sessionCookie(timedOut))
+	greeting := httptest.NewRecorder()
+	HandleRequest(greeting, request)
+
+	assert.Equal(t, timedOut.Code, 503, "Response status code is 503")
+	assert.Equal(t, greeting.Body.String(), "mario false", "Changes after the timeout are discarded")
+}
+
+func registerSessionLogin(sessions *Sessions) {
+	Intercept("/.*", sessions.Intercept)
+	Post("/login", func(req RequestEntity, resp *ResponseEntity) {
+		req.Session.Regenerate()
+		req.Session.Set("user", req.Request.URL.Query().Get("user"))
+		resp.Status = 200
+	})
+}
+
+func login(user string) *http.Cookie {
+	recorder := httptest.NewRecorder()
+	HandleRequest(recorder, httptest.NewRequest(http.MethodPost, "/login?user="+user, strings.NewReader("")))
+
+	return sessionCookie(recorder)
+}
+
+func registerSessionCounter(sessions *Sessions) {
+	Intercept("/.*", sessions.Intercept)
+	Get("/persons", func(req RequestEntity, resp *ResponseEntity) {
+		count, _ := req.Session.Get("count").(float64)
+		req.Session.Set("count", count+1)
+		resp.Status = 200
+		resp.Body = []byte(strconv.Itoa(int(count + 1)))
+	})
+}
+
+func getWithSession(cookie *http.Cookie) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(http.MethodGet, "/persons", strings.NewReader(""))
+
+	if cookie != nil {
+		request.AddCookie(cookie)
+	}
+
+	recorder := httptest.NewRecorder()
+
+	HandleRequest(recorder, request)
+
+	return recorder
+}
+
+func sessionCookie(recorder *httptest.ResponseRecorder) *http.Cookie {
+	for _, cookie := range recorder.Result().Cookies() {
+		if cookie.Name == "session" {
+			return cookie
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,63 @@
+package cable
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Timeout returns an interceptor cancelling the context of the request after timeout.
+// If the request handler has not returned by then, cable responds with status, which
+// should be 503 Service Unavailable or 504 Gateway Timeout. Register it for all routes,
+// a group or a single route with cable.Intercept(pattern, cable.Timeout(timeout, status)).
+func Timeout(timeout time.Duration, status int) func(requestEntity RequestEntity, response *ResponseEntity, next func(requestEntity RequestEntity, response *ResponseEntity)) {
+	return func(requestEntity RequestEntity, response *ResponseEntity, next func(requestEntity RequestEntity, response *ResponseEntity)) {
+		ctx, cancel := context.WithTimeout(requestEntity.Request.Context(), timeout)
+		defer cancel()
+
+		requestEntity.Request = requestEntity.Request.WithContext(ctx)
+
+		// the handler writes to its own entity, so writes after the timeout are discarded
+		handled := copyResponseEntity(*response)
+		done := make(chan interface{}, 1)
+
+		go func() {
+			defer func() {
+				done <- recover()
+			}()
+
+			next(requestEntity, &handled)
+		}()
+
+		select {
+		case p := <-done:
+			if p != nil {
+				panic(p)
+			}
+
+			*response = handled
+		case <-ctx.Done():
+			logger.Warn("Request timed out",
+				zap.String("Path", requestEntity.Request.URL.Path),
+				zap.String("Method", requestEntity.Request.Method),
+				zap.Duration("Timeout", timeout))
+
+			setError(response, requestEntity.Request, status, http.StatusText(status))
+		}
+	}
+}
+
+func copyResponseEntity(response ResponseEntity) ResponseEntity {
+	header := make(map[string]string, len(response.Header))
+
+	for key, value := range response.Header {
+		header[key] = value
+	}
+
+	response.Header = header
+	response.Cookies = append([]*http.Cookie{}, response.Cookies...)
+
+	return response
+}
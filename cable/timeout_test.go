@@ -0,0 +1,81 @@
+package cable
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	assert "github.com/stfsy/golang-assert"
+)
+
+func TestTimeoutResponds503(t *testing.T) {
+	reset()
+	cancelled := make(chan bool, 1)
+	Intercept("/.*", Timeout(10*time.Millisecond, http.StatusServiceUnavailable))
+	Get("/slow", func(req RequestEntity, resp *ResponseEntity) {
+		<-req.Request.Context().Done()
+		cancelled <- true
+
+		// written after the timeout, must be discarded
+		resp.Status = 200
+		resp.Body = []byte("Too late")
+	})
+
+	recorder := getSlow()
+
+	assert.Equal(t, recorder.Code, 503, "Response status code is 503")
+	assert.Equal(t, recorder.Body.String(), `{"type":"about:blank","title":"Service Unavailable","status":503,"instance":"/slow"}`, "Response body describes the error")
+	assert.Equal(t, <-cancelled, true, "Context of the request is cancelled")
+}
+
+func TestTimeoutResponds504(t *testing.T) {
+	reset()
+	Intercept("/slow", Timeout(10*time.Millisecond, http.StatusGatewayTimeout))
+	Get("/slow", func(req RequestEntity, resp *ResponseEntity) {
+		time.Sleep(100 * time.Millisecond)
+	})
+
+	recorder := getSlow()
+
+	assert.Equal(t, recorder.Code, 504, "Response status code is 504")
+}
+
+func TestTimeoutNotExceededResponds200(t *testing.T) {
+	reset()
+	Intercept("/.*", Timeout(time.Second, http.StatusServiceUnavailable))
+	Get("/slow", func(req RequestEntity, resp *ResponseEntity) {
+		resp.Status = 200
+		resp.Header = map[string]string{"X-Slow": "no"}
+		resp.Body = []byte("In time")
+	})
+
+	recorder := getSlow()
+
+	assert.Equal(t, recorder.Code, 200, "Response status code is 200")
+	assert.Equal(t, recorder.Header().Get("X-Slow"), "no", "Response header is set")
+	assert.Equal(t, recorder.Body.String(), "In time", "Response body is set")
+}
+
+func TestShortestTimeoutApplies(t *testing.T) {
+	reset()
+	Intercept("/.*", Timeout(time.Second, http.StatusServiceUnavailable))
+	Intercept("/slow", Timeout(10*time.Millisecond, http.StatusGatewayTimeout))
+	Get("/slow", func(req RequestEntity, resp *ResponseEntity) {
+		<-req.Request.Context().Done()
+	})
+
+	recorder := getSlow()
+
+	assert.Equal(t, recorder.Code, 504, "Response status code is 504")
+}
+
+func getSlow() *httptest.ResponseRecorder {
+	request := httptest.NewRequest(http.MethodGet, "/slow", strings.NewReader(""))
+	recorder := httptest.NewRecorder()
+
+	HandleRequest(recorder, request)
+
+	return recorder
+}
@@ -0,0 +1,262 @@
+package cable
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+var (
+	trustedProxies      = []*net.IPNet{}
+	trustedProxiesMutex sync.RWMutex
+)
+
+// TrustProxies honours Forwarded and X-Forwarded-* headers of requests sent by the given
+// networks, e.g. 10.0.0.0/8 or 2001:db8::/32. Single addresses are accepted as well.
+func TrustProxies(cidrs ...string) error {
+	networks, err := parseNetworks(cidrs)
+
+	if err != nil {
+		return err
+	}
+
+	trustedProxiesMutex.Lock()
+	defer trustedProxiesMutex.Unlock()
+
+	trustedProxies = networks
+
+	return nil
+}
+
+func isTrustedProxy(ip net.IP) bool {
+	trustedProxiesMutex.RLock()
+	defer trustedProxiesMutex.RUnlock()
+
+	return containsIP(trustedProxies, ip)
+}
+
+// parseNetworks accepts networks in CIDR notation and single IPv4 or IPv6 addresses
+func parseNetworks(cidrs []string) ([]*net.IPNet, error) {
+	networks := []*net.IPNet{}
+
+	for _, cidr := range cidrs {
+		if !strings.Contains(cidr, "/") {
+			if ip := net.ParseIP(cidr); ip != nil && ip.To4() != nil {
+				cidr = cidr + "/32"
+			} else {
+				cidr = cidr + "/128"
+			}
+		}
+
+		_, network, err := net.ParseCIDR(cidr)
+
+		if err != nil {
+			return nil, err
+		}
+
+		networks = append(networks, network)
+	}
+
+	return networks, nil
+}
+
+func containsIP(networks []*net.IPNet, ip net.IP) bool {
+	for _, network := range networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ClientIP returns the address of the client, resolved through trusted proxies
+func ClientIP(request *http.Request) string {
+	if scope := scopeOf(request); len(scope.clientIP) > 0 {
+		return scope.clientIP
+	}
+
+	return remoteHost(request)
+}
+
+// Scheme returns http or https as requested by the client, resolved through trusted proxies
+func Scheme(request *http.Request) string {
+	if scope := scopeOf(request); len(scope.scheme) > 0 {
+		return scope.scheme
+	}
+
+	return schemeOf(request)
+}
+
+// Host returns the host requested by the client, resolved through trusted proxies
+func Host(request *http.Request) string {
+	if scope := scopeOf(request); len(scope.host) > 0 {
+		return scope.host
+	}
+
+	return request.Host
+}
+
+// forwardedHop is what a proxy knows about the hop it received the request from
+type forwardedHop struct {
+	For   string
+	Proto string
+	Host  string
+}
+
+func resolveClient(request *http.Request, scope *requestScope) {
+	scope.clientIP = remoteHost(request)
+	scope.scheme = schemeOf(request)
+	scope.host = request.Host
+
+	if ip := net.ParseIP(scope.clientIP); ip == nil || !isTrustedProxy(ip) {
+		return
+	}
+
+	hops := parseForwarded(request.Header.Values("Forwarded"))
+
+	if len(hops) == 0 {
+		hops = parseXForwarded(request.Header)
+	}
+
+	/*
+	* Walk the chain of proxies from the nearest one to the client and stop at the
+	* first address that is not trusted. Addresses left of it may be forged.
+	 */
+	for i := len(hops) - 1; i >= 0; i-- {
+		// every hop we get to was described by a trusted proxy
+		if len(hops[i].Proto) > 0 {
+			scope.scheme = strings.ToLower(hops[i].Proto)
+		}
+
+		if len(hops[i].Host) > 0 {
+			scope.host = hops[i].Host
+		}
+
+		ip := net.ParseIP(hops[i].For)
+
+		if ip == nil {
+			break
+		}
+
+		scope.clientIP = ip.String()
+
+		if !isTrustedProxy(ip) {
+			break
+		}
+	}
+}
+
+/*
+* Forwarded: for=192.0.2.60;proto=http;by=203.0.113.43, for="[2001:db8:cafe::17]:4711"
+*
+* https://tools.ietf.org/html/rfc7239#section-4
+ */
+func parseForwarded(values []string) []forwardedHop {
+	hops := []forwardedHop{}
+
+	for _, value := range values {
+		for _, element := range strings.Split(value, ",") {
+			hop := forwardedHop{}
+
+			for _, pair := range strings.Split(element, ";") {
+				kv := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+
+				if len(kv) != 2 {
+					continue
+				}
+
+				v := strings.Trim(kv[1], "\"")
+
+				switch strings.ToLower(kv[0]) {
+				case "for":
+					hop.For = stripPort(v)
+				case "proto":
+					hop.Proto = v
+				case "host":
+					hop.Host = v
+				}
+			}
+
+			hops = append(hops, hop)
+		}
+	}
+
+	return hops
+}
+
+// parseXForwarded uses the de-facto standard headers. Proto and host are taken from
+// the nearest proxy and attributed to every hop.
+func parseXForwarded(header http.Header) []forwardedHop {
+	hops := []forwardedHop{}
+
+	for _, value := range header.Values("X-Forwarded-For") {
+		for _, address := range strings.Split(value, ",") {
+			hops = append(hops, forwardedHop{For: stripPort(strings.TrimSpace(address))})
+		}
+	}
+
+	if len(hops) == 0 {
+		hops = append(hops, forwardedHop{})
+	}
+
+	for i := range hops {
+		hops[i].Proto = lastListValue(header.Values("X-Forwarded-Proto"))
+		hops[i].Host = lastListValue(header.Values("X-Forwarded-Host"))
+	}
+
+	return hops
+}
+
+func lastListValue(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+
+	list := strings.Split(values[len(values)-1], ",")
+
+	return strings.TrimSpace(list[len(list)-1])
+}
+
+func stripPort(address string) string {
+	if host, _, err := net.SplitHostPort(address); err == nil {
+		return host
+	}
+
+	return strings.Trim(address, "[]")
+}
+
+func remoteHost(request *http.Request) string {
+	host, _, err := net.SplitHostPort(request.RemoteAddr)
+
+	if err != nil {
+		return request.RemoteAddr
+	}
+
+	return host
+}
+
+func schemeOf(request *http.Request) string {
+	if request.TLS != nil {
+		return "https"
+	}
+
+	return "http"
+}
+
+// Redirect responds with status and a Location header. Locations starting with a slash
+// are resolved against the scheme and host requested by the client.
+func (r *ResponseEntity) Redirect(status int, location string) {
+	if strings.HasPrefix(location, "/") && !strings.HasPrefix(location, "//") && r.Request != nil {
+		location = fmt.Sprintf("%s://%s%s", Scheme(r.Request), Host(r.Request), location)
+	}
+
+	if r.Header == nil {
+		r.Header = map[string]string{}
+	}
+
+	r.Header["Location"] = location
+	r.Status = status
+}
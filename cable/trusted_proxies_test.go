@@ -0,0 +1,118 @@
+package cable
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	assert "github.com/stfsy/golang-assert"
+)
+
+func TestUntrustedProxyHeadersAreIgnored(t *testing.T) {
+	reset()
+	registerGetClient()
+
+	recorder := getClient("198.51.100.7:1234", map[string]string{
+		"X-Forwarded-For":   "203.0.113.1",
+		"X-Forwarded-Proto": "https"})
+
+	assert.Equal(t, recorder.Body.String(), "198.51.100.7 http example.com", "Remote address is the client")
+}
+
+func TestXForwardedHeadersOfTrustedProxy(t *testing.T) {
+	reset()
+	TrustProxies("10.0.0.0/8")
+	registerGetClient()
+
+	recorder := getClient("10.0.0.2:1234", map[string]string{
+		"X-Forwarded-For":   "203.0.113.9, 203.0.113.1, 10.0.0.1",
+		"X-Forwarded-Proto": "https",
+		"X-Forwarded-Host":  "api.example.com"})
+
+	assert.Equal(t, recorder.Body.String(), "203.0.113.1 https api.example.com", "Client is the first untrusted hop")
+}
+
+func TestForwardedHeaderOfTrustedProxy(t *testing.T) {
+	reset()
+	TrustProxies("10.0.0.0/8", "2001:db8::1")
+	registerGetClient()
+
+	recorder := getClient("[2001:db8::1]:1234", map[string]string{
+		"Forwarded":       `for="[2001:db8:cafe::17]:4711";proto=https;host=api.example.com, for=10.1.2.3`,
+		"X-Forwarded-For": "203.0.113.1"})
+
+	assert.Equal(t, recorder.Body.String(), "2001:db8:cafe::17 https api.example.com", "Forwarded takes precedence")
+}
+
+func TestForwardedUnknownStopsAtTrustedProxy(t *testing.T) {
+	reset()
+	TrustProxies("10.0.0.0/8")
+	registerGetClient()
+
+	recorder := getClient("10.0.0.2:1234", map[string]string{
+		"Forwarded": "for=unknown, for=10.0.0.1"})
+
+	assert.Equal(t, recorder.Body.String(), "10.0.0.1 http example.com", "Last known hop is the client")
+}
+
+func TestRateLimitUsesResolvedClientIP(t *testing.T) {
+	reset()
+	TrustProxies("10.0.0.0/8")
+	registerGetPersons()
+	limiter := NewRateLimiter(RateLimit{Requests: 1, Window: time.Minute})
+	registerRateLimiter(limiter)
+
+	getForwarded("/persons", "10.0.0.2:1234", map[string]string{"X-Forwarded-For": "203.0.113.1"})
+	recorder := getForwarded("/persons", "10.0.0.2:1234", map[string]string{"X-Forwarded-For": "203.0.113.2"})
+
+	assert.Equal(t, recorder.Code, 200, "Clients behind the same proxy are limited separately")
+}
+
+func TestRedirectUsesResolvedSchemeAndHost(t *testing.T) {
+	reset()
+	TrustProxies("10.0.0.0/8")
+	Get("/persons", func(req RequestEntity, resp *ResponseEntity) {
+		resp.Redirect(http.StatusMovedPermanently, "/people")
+	})
+
+	recorder := getForwarded("/persons", "10.0.0.2:1234", map[string]string{
+		"X-Forwarded-Proto": "https",
+		"X-Forwarded-Host":  "api.example.com"})
+
+	assert.Equal(t, recorder.Code, 301, "Response status code is 301")
+	assert.Equal(t, recorder.Header().Get("Location"), "https://api.example.com/people", "Location is absolute")
+}
+
+func TestTrustProxiesRejectsInvalidNetwork(t *testing.T) {
+	err := TrustProxies("10.0.0.0/33")
+
+	assert.NotEqual(t, err, nil, "Error should not be nil")
+}
+
+func registerGetClient() {
+	Get("/client", func(req RequestEntity, resp *ResponseEntity) {
+		resp.Status = 200
+		resp.Body = []byte(req.ClientIP + " " + req.Scheme + " " + req.Host)
+	})
+}
+
+func getClient(remoteAddr string, header map[string]string) *httptest.ResponseRecorder {
+	return getForwarded("/client", remoteAddr, header)
+}
+
+func getForwarded(path string, remoteAddr string, header map[string]string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(http.MethodGet, path, strings.NewReader(""))
+	request.RemoteAddr = remoteAddr
+
+	for key, value := range header {
+		request.Header.Set(key, value)
+	}
+
+	recorder := httptest.NewRecorder()
+
+	HandleRequest(recorder, request)
+
+	return recorder
+}
@@ -0,0 +1,203 @@
+package cable
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// TypedHandler receives the bound request and returns the response body
+type TypedHandler[In any, Out any] func(requestEntity RequestEntity, in In) (Out, error)
+
+// StatusCoder is implemented by response bodies of typed handlers not responding 200 OK
+type StatusCoder interface {
+	StatusCode() int
+}
+
+// GetTyped binds query parameters to the fields of In, named like the query tag of a
+// field or its lower cased name, and marshals Out in a media type the client accepts
+func GetTyped[In any, Out any](pattern string, handler TypedHandler[In, Out]) {
+	registerHandler(http.MethodGet, pattern, typed(handler))
+}
+
+// DeleteTyped binds query parameters like GetTyped
+func DeleteTyped[In any, Out any](pattern string, handler TypedHandler[In, Out]) {
+	registerHandler(http.MethodDelete, pattern, typed(handler))
+}
+
+// PostTyped unmarshals the request body to In and marshals Out in a media type the client accepts
+func PostTyped[In any, Out any](pattern string, handler TypedHandler[In, Out]) {
+	registerHandler(http.MethodPost, pattern, typed(handler))
+}
+
+func PutTyped[In any, Out any](pattern string, handler TypedHandler[In, Out]) {
+	registerHandler(http.MethodPut, pattern, typed(handler))
+}
+
+func PatchTyped[In any, Out any](pattern string, handler TypedHandler[In, Out]) {
+	registerHandler(http.MethodPatch, pattern, typed(handler))
+}
+
+func typed[In any, Out any](handler TypedHandler[In, Out]) func(requestEntity RequestEntity, response *ResponseEntity) {
+	return Handler(func(requestEntity RequestEntity, response *ResponseEntity) error {
+		// negotiate first, so requests that cannot be answered have no side effects
+		plugin, err := negotiatePlugin(requestEntity.Request)
+
+		if err != nil {
+			return err
+		}
+
+		var in In
+
+		if err := bind(requestEntity.Request, &in); err != nil {
+			return err
+		}
+
+		out, err := handler(requestEntity, in)
+
+		if err != nil {
+			return err
+		}
+
+		body, err := plugin.Produce(out)
+
+		if err != nil {
+			return err
+		}
+
+		response.Status = http.StatusOK
+
+		if statusCoder, ok := interface{}(out).(StatusCoder); ok {
+			response.Status = statusCoder.StatusCode()
+		}
+
+		response.SetHeader(contentTypeHeader, concreteMediaType(plugin))
+		response.Body = body
+
+		return nil
+	})
+}
+
+// bind reads query parameters of requests without body and the body of all others,
+// then validates target
+func bind(request *http.Request, target interface{}) error {
+	if request.Method == http.MethodGet || request.Method == http.MethodDelete || request.Method == http.MethodHead {
+		if err := bindQuery(request.URL.Query(), target); err != nil {
+			return NewHTTPError(http.StatusBadRequest, "invalid_query", err.Error()).Wrap(err)
+		}
+
+		return Validate(target)
+	}
+
+	err := UnmarshalBody(target, request)
+
+	if err != nil && !errors.Is(err, ErrBodyTooLarge) && !errors.Is(err, ErrUnsupportedMediaType) {
+		return NewHTTPError(http.StatusBadRequest, "malformed_body", "request body is malformed").Wrap(err)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return Validate(target)
+}
+
+// bindQuery sets the fields of the struct target points to from query parameters named
+// like the query tag of the field or the lower cased field name. Strings, booleans,
+// numbers and slices of these are supported.
+func bindQuery(values url.Values, target interface{}) error {
+	value := reflect.ValueOf(target).Elem()
+
+	if value.Kind() != reflect.Struct {
+		return nil
+	}
+
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		name := field.Tag.Get("query")
+
+		if name == "-" || !field.IsExported() {
+			continue
+		}
+
+		if len(name) == 0 {
+			name = strings.ToLower(field.Name)
+		}
+
+		parameters, ok := values[name]
+
+		if !ok {
+			continue
+		}
+
+		if err := setQueryValue(value.Field(i), parameters); err != nil {
+			return fmt.Errorf("parameter %v: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+func setQueryValue(field reflect.Value, parameters []string) error {
+	if field.Kind() == reflect.Slice {
+		slice := reflect.MakeSlice(field.Type(), len(parameters), len(parameters))
+
+		for i, parameter := range parameters {
+			if err := setScalar(slice.Index(i), parameter); err != nil {
+				return err
+			}
+		}
+
+		field.Set(slice)
+		return nil
+	}
+
+	return setScalar(field, parameters[0])
+}
+
+func setScalar(field reflect.Value, parameter string) error {
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(parameter)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(parameter)
+
+		if err != nil {
+			return err
+		}
+
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(parameter, 10, field.Type().Bits())
+
+		if err != nil {
+			return err
+		}
+
+		field.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(parameter, 10, field.Type().Bits())
+
+		if err != nil {
+			return err
+		}
+
+		field.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(parameter, field.Type().Bits())
+
+		if err != nil {
+			return err
+		}
+
+		field.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported type %v", field.Type())
+	}
+
+	return nil
+}
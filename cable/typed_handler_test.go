@@ -0,0 +1,131 @@
+package cable
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	assert "github.com/stfsy/golang-assert"
+)
+
+type createdPerson Person
+
+func (c createdPerson) StatusCode() int {
+	return http.StatusCreated
+}
+
+type personQuery struct {
+	Name  string `query:"name"`
+	Page  int
+	Sizes []uint `query:"size"`
+}
+
+func TestPostTypedBindsBody(t *testing.T) {
+	reset()
+	registerPostTyped()
+
+	recorder := requestTyped(http.MethodPost, "/persons", "application/json", "application/xml", jsonBody)
+
+	assert.Equal(t, recorder.Code, 201, "Response status code is 201")
+	assert.Equal(t, recorder.Header().Get("Content-Type"), "application/xml", "Response is XML")
+	assert.Equal(t, recorder.Body.String(), "<createdPerson><FirstName>Mario</FirstName><SecondName>Micelli</SecondName></createdPerson>", "Response body is marshalled")
+}
+
+func TestPostTypedUnsupportedContentTypeResponds415(t *testing.T) {
+	reset()
+	registerPostTyped()
+
+	recorder := requestTyped(http.MethodPost, "/persons", "text/csv", "application/json", "Mario,Micelli")
+
+	assert.Equal(t, recorder.Code, 415, "Response status code is 415")
+}
+
+func TestPostTypedMalformedBodyResponds400(t *testing.T) {
+	reset()
+	registerPostTyped()
+
+	recorder := requestTyped(http.MethodPost, "/persons", "application/json", "application/json", `{"firstName":`)
+
+	assert.Equal(t, recorder.Code, 400, "Response status code is 400")
+	assert.Equal(t, strings.Contains(recorder.Body.String(), `"code":"malformed_body"`), true, "Response body describes the error")
+}
+
+func TestPostTypedUnacceptableResponds406(t *testing.T) {
+	reset()
+	registerPostTyped()
+
+	recorder := requestTyped(http.MethodPost, "/persons", "application/json", "text/csv", jsonBody)
+
+	assert.Equal(t, recorder.Code, 406, "Response status code is 406")
+}
+
+func TestPostTypedUnacceptableDoesNotCallHandler(t *testing.T) {
+	reset()
+	called := false
+	PostTyped("/persons", func(req RequestEntity, person Person) (createdPerson, error) {
+		called = true
+		return createdPerson(person), nil
+	})
+
+	recorder := requestTyped(http.MethodPost, "/persons", "application/json", "text/csv", jsonBody)
+
+	assert.Equal(t, recorder.Code, 406, "Response status code is 406")
+	assert.Equal(t, called, false, "Handler is not called")
+}
+
+func TestGetTypedBindsQuery(t *testing.T) {
+	reset()
+	GetTyped("/persons", func(req RequestEntity, query personQuery) (personQuery, error) {
+		return query, nil
+	})
+
+	recorder := requestTyped(http.MethodGet, "/persons?name=Mario&page=2&size=10&size=20", "", "application/json", "")
+
+	assert.Equal(t, recorder.Code, 200, "Response status code is 200")
+	assert.Equal(t, recorder.Body.String(), `{"Name":"Mario","Page":2,"Sizes":[10,20]}`, "Query is bound")
+}
+
+func TestGetTypedInvalidQueryResponds400(t *testing.T) {
+	reset()
+	GetTyped("/persons", func(req RequestEntity, query personQuery) (personQuery, error) {
+		return query, nil
+	})
+
+	recorder := requestTyped(http.MethodGet, "/persons?page=two", "", "application/json", "")
+
+	assert.Equal(t, recorder.Code, 400, "Response status code is 400")
+}
+
+func TestTypedHandlerErrorIsMapped(t *testing.T) {
+	reset()
+	MapError(errPersonNotFound, NewHTTPError(http.StatusNotFound, "person_not_found", "person not found"))
+	GetTyped("/persons", func(req RequestEntity, query personQuery) (Person, error) {
+		return Person{}, errPersonNotFound
+	})
+
+	recorder := requestTyped(http.MethodGet, "/persons", "", "application/json", "")
+
+	assert.Equal(t, recorder.Code, 404, "Response status code is 404")
+}
+
+func registerPostTyped() {
+	PostTyped("/persons", func(req RequestEntity, person Person) (createdPerson, error) {
+		return createdPerson(person), nil
+	})
+}
+
+func requestTyped(method string, path string, contentType string, accept string, body string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, path, strings.NewReader(body))
+
+	if len(contentType) > 0 {
+		request.Header.Set("Content-Type", contentType)
+	}
+
+	request.Header.Set("Accept", accept)
+	recorder := httptest.NewRecorder()
+
+	HandleRequest(recorder, request)
+
+	return recorder
+}
@@ -0,0 +1,418 @@
+package cable
+
+import (
+	"fmt"
+	"net/mail"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// ValidatorFunc reports whether value satisfies a rule with param, e.g. 3 for min=3
+type ValidatorFunc func(value reflect.Value, param string) bool
+
+// ValidationError describes a field violating a rule
+type ValidationError struct {
+	// Field is the path of the field, named like its json tag, e.g. addresses[0].street
+	Field   string `json:"field" xml:"field"`
+	Rule    string `json:"rule" xml:"rule"`
+	Message string `json:"message" xml:"message"`
+}
+
+// ValidationErrors is returned by Validate and responded to with 422 Unprocessable Entity
+type ValidationErrors []ValidationError
+
+func (v ValidationErrors) Error() string {
+	messages := []string{}
+
+	for _, err := range v {
+		messages = append(messages, err.Field+" "+err.Message)
+	}
+
+	return strings.Join(messages, ", ")
+}
+
+var (
+	defaultValidators = map[string]ValidatorFunc{
+		"min":    validateMin,
+		"max":    validateMax,
+		"len":    validateLen,
+		"oneof":  validateOneOf,
+		"email":  validateEmail,
+		"regexp": validateRegexp}
+	validators = copyValidators(defaultValidators)
+	regexps    = sync.Map{}
+	// structRules caches the parsed validate tags per struct type
+	structRules = sync.Map{}
+)
+
+// RegisterValidator adds a rule usable in validate tags, e.g. cable.RegisterValidator("even", even)
+// for fields tagged `validate:"even"`
+func RegisterValidator(name string, validator ValidatorFunc) {
+	validators[name] = validator
+	clearStructRules()
+
+	logger.Info("Registered Validator",
+		zap.String("Rule", name))
+}
+
+func copyValidators(validators map[string]ValidatorFunc) map[string]ValidatorFunc {
+	copied := make(map[string]ValidatorFunc, len(validators))
+
+	for name, validator := range validators {
+		copied[name] = validator
+	}
+
+	return copied
+}
+
+/*
+* Validate checks the fields of the struct target points to against the rules of their
+* validate tags, separated by commas:
+*
+*   required       the field is not the zero value
+*   omitempty      skips all other rules if the field is the zero value
+*   min=n, max=n   limits numbers or the length of strings, slices and maps
+*   len=n          is the exact length of strings, slices and maps
+*   oneof=a b      is one of the values separated by spaces
+*   email          is an email address
+*   regexp=^a+$    matches the regular expression, which must not contain commas
+*   dive           applies the following rules to the elements of a slice
+*
+* Nested structs and structs in slices are validated as well. Validate returns
+* ValidationErrors if any rule is violated. Tags are parsed once per type, a tag with
+* an unknown rule or an invalid regexp makes Validate fail with a different error,
+* which is responded to with 500 Internal Server Error.
+ */
+func Validate(target interface{}) error {
+	errs := ValidationErrors{}
+
+	if err := validateField(reflect.ValueOf(target), "", nil, &errs); err != nil {
+		return err
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+type validationRule struct {
+	name      string
+	param     string
+	validator ValidatorFunc
+}
+
+type validatedField struct {
+	index int
+	name  string
+	rules []validationRule
+}
+
+type validatedStruct struct {
+	fields []validatedField
+	err    error
+}
+
+func clearStructRules() {
+	structRules.Range(func(key interface{}, value interface{}) bool {
+		structRules.Delete(key)
+		return true
+	})
+}
+
+// parseStruct parses the validate tags of all exported fields of t once
+func parseStruct(t reflect.Type) validatedStruct {
+	if cached, ok := structRules.Load(t); ok {
+		return cached.(validatedStruct)
+	}
+
+	parsed := validatedStruct{}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("validate")
+
+		if !field.IsExported() || tag == "-" {
+			continue
+		}
+
+		rules, err := parseRules(tag)
+
+		if err != nil {
+			logger.Error("Invalid validate tag",
+				zap.String("Type", t.String()),
+				zap.String("Field", field.Name),
+				zap.Error(err))
+
+			parsed = validatedStruct{err: fmt.Errorf("validate tag of %v.%v: %w", t, field.Name, err)}
+			break
+		}
+
+		parsed.fields = append(parsed.fields, validatedField{index: i, name: jsonFieldName(field), rules: rules})
+	}
+
+	cached, _ := structRules.LoadOrStore(t, parsed)
+
+	return cached.(validatedStruct)
+}
+
+func parseRules(tag string) ([]validationRule, error) {
+	rules := []validationRule{}
+
+	for _, rule := range strings.Split(tag, ",") {
+		if rule = strings.TrimSpace(rule); len(rule) == 0 {
+			continue
+		}
+
+		nameAndParam := strings.SplitN(rule, "=", 2)
+		parsed := validationRule{name: nameAndParam[0]}
+
+		if len(nameAndParam) == 2 {
+			parsed.param = nameAndParam[1]
+		}
+
+		switch parsed.name {
+		case "omitempty", "required", "dive":
+		default:
+			validator, ok := validators[parsed.name]
+
+			if !ok {
+				return nil, fmt.Errorf("unknown rule %q", parsed.name)
+			}
+
+			if parsed.name == "regexp" {
+				compiled, err := regexp.Compile(parsed.param)
+
+				if err != nil {
+					return nil, err
+				}
+
+				regexps.LoadOrStore(parsed.param, compiled)
+			}
+
+			parsed.validator = validator
+		}
+
+		rules = append(rules, parsed)
+	}
+
+	return rules, nil
+}
+
+func validateField(value reflect.Value, path string, rules []validationRule, errs *ValidationErrors) error {
+	for i, rule := range rules {
+		switch rule.name {
+		case "omitempty":
+			if !value.IsValid() || value.IsZero() {
+				return nil
+			}
+		case "required":
+			if !value.IsValid() || value.IsZero() {
+				*errs = append(*errs, ValidationError{Field: path, Rule: rule.name, Message: "is required"})
+				return nil
+			}
+		case "dive":
+			value = indirect(value)
+
+			if value.Kind() == reflect.Slice || value.Kind() == reflect.Array {
+				for j := 0; j < value.Len(); j++ {
+					if err := validateField(value.Index(j), fmt.Sprintf("%s[%d]", path, j), rules[i+1:], errs); err != nil {
+						return err
+					}
+				}
+			}
+
+			return nil
+		default:
+			if v := indirect(value); v.IsValid() && !rule.validator(v, rule.param) {
+				*errs = append(*errs, ValidationError{Field: path, Rule: rule.name, Message: validationMessage(rule, v)})
+			}
+		}
+	}
+
+	value = indirect(value)
+
+	switch value.Kind() {
+	case reflect.Struct:
+		return validateStruct(value, path, errs)
+	case reflect.Slice, reflect.Array:
+		if element := value.Type().Elem(); element.Kind() == reflect.Struct || (element.Kind() == reflect.Ptr && element.Elem().Kind() == reflect.Struct) {
+			for i := 0; i < value.Len(); i++ {
+				if err := validateField(value.Index(i), fmt.Sprintf("%s[%d]", path, i), nil, errs); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func validateStruct(value reflect.Value, path string, errs *ValidationErrors) error {
+	parsed := parseStruct(value.Type())
+
+	if parsed.err != nil {
+		return parsed.err
+	}
+
+	for _, field := range parsed.fields {
+		name := field.name
+
+		if len(path) > 0 {
+			name = path + "." + name
+		}
+
+		if err := validateField(value.Field(field.index), name, field.rules, errs); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// jsonFieldName names fields like clients send them
+func jsonFieldName(field reflect.StructField) string {
+	if name := strings.Split(field.Tag.Get("json"), ",")[0]; len(name) > 0 && name != "-" {
+		return name
+	}
+
+	return field.Name
+}
+
+func indirect(value reflect.Value) reflect.Value {
+	for value.IsValid() && (value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface) {
+		if value.IsNil() {
+			return reflect.Value{}
+		}
+
+		value = value.Elem()
+	}
+
+	return value
+}
+
+func validationMessage(rule validationRule, value reflect.Value) string {
+	unit := ""
+
+	if hasLength(value) {
+		unit = " characters"
+
+		if value.Kind() != reflect.String {
+			unit = " elements"
+		}
+	}
+
+	switch rule.name {
+	case "min":
+		return "must be at least " + rule.param + unit
+	case "max":
+		return "must be at most " + rule.param + unit
+	case "len":
+		return "must have " + rule.param + unit
+	case "oneof":
+		return "must be one of " + rule.param
+	case "email":
+		return "must be an email address"
+	case "regexp":
+		return "must match " + rule.param
+	}
+
+	return "must be " + rule.name
+}
+
+func hasLength(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.String, reflect.Slice, reflect.Array, reflect.Map:
+		return true
+	}
+
+	return false
+}
+
+// size is the length of strings, slices and maps and the value of numbers
+func size(value reflect.Value) (float64, bool) {
+	switch value.Kind() {
+	case reflect.String:
+		return float64(len([]rune(value.String()))), true
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(value.Len()), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(value.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(value.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return value.Float(), true
+	}
+
+	return 0, false
+}
+
+func compareSize(value reflect.Value, param string, compare func(size float64, limit float64) bool) bool {
+	limit, err := strconv.ParseFloat(param, 64)
+	s, ok := size(value)
+
+	return err == nil && ok && compare(s, limit)
+}
+
+func validateMin(value reflect.Value, param string) bool {
+	return compareSize(value, param, func(size float64, limit float64) bool { return size >= limit })
+}
+
+func validateMax(value reflect.Value, param string) bool {
+	return compareSize(value, param, func(size float64, limit float64) bool { return size <= limit })
+}
+
+func validateLen(value reflect.Value, param string) bool {
+	return hasLength(value) && compareSize(value, param, func(size float64, limit float64) bool { return size == limit })
+}
+
+func validateOneOf(value reflect.Value, param string) bool {
+	actual := fmt.Sprint(value.Interface())
+
+	for _, allowed := range strings.Fields(param) {
+		if actual == allowed {
+			return true
+		}
+	}
+
+	return false
+}
+
+func validateEmail(value reflect.Value, param string) bool {
+	if value.Kind() != reflect.String {
+		return false
+	}
+
+	address, err := mail.ParseAddress(value.String())
+
+	return err == nil && address.Address == value.String()
+}
+
+func validateRegexp(value reflect.Value, param string) bool {
+	if value.Kind() != reflect.String {
+		return false
+	}
+
+	compiled, ok := regexps.Load(param)
+
+	if !ok {
+		r, err := regexp.Compile(param)
+
+		if err != nil {
+			logger.Error("Invalid validation regexp",
+				zap.String("Regexp", param),
+				zap.Error(err))
+			return false
+		}
+
+		compiled, _ = regexps.LoadOrStore(param, r)
+	}
+
+	return compiled.(*regexp.Regexp).MatchString(value.String())
+}
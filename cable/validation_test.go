@@ -0,0 +1,167 @@
+package cable
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	assert "github.com/stfsy/golang-assert"
+)
+
+type address struct {
+	Street string `json:"street" validate:"required"`
+	Zip    string `json:"zip" validate:"len=5,regexp=^[0-9]+$"`
+}
+
+type registration struct {
+	Name      string    `json:"name" validate:"required,min=2,max=20"`
+	Email     string    `json:"email" validate:"omitempty,email"`
+	Age       int       `json:"age" validate:"min=18,max=130"`
+	Plan      string    `json:"plan" validate:"oneof=free pro"`
+	Tags      []string  `json:"tags" validate:"max=2,dive,min=3"`
+	Address   address   `json:"address"`
+	Addresses []address `json:"addresses"`
+}
+
+func validRegistration() registration {
+	return registration{
+		Name:      "Mario",
+		Email:     "mario@example.com",
+		Age:       30,
+		Plan:      "pro",
+		Tags:      []string{"plumber"},
+		Address:   address{Street: "Mushroom Road", Zip: "12345"},
+		Addresses: []address{{Street: "Castle Road", Zip: "54321"}}}
+}
+
+func TestValidatePasses(t *testing.T) {
+	r := validRegistration()
+
+	assert.Equal(t, Validate(&r), nil, "Error should be nil")
+}
+
+func TestValidateReportsEachField(t *testing.T) {
+	r := validRegistration()
+	r.Name = "M"
+	r.Email = "mario"
+	r.Age = 12
+	r.Plan = "gold"
+	r.Tags = []string{"a", "plumber"}
+	r.Address.Street = ""
+	r.Addresses[0].Zip = "1234x"
+
+	err := Validate(&r)
+
+	assert.Equal(t, err, ValidationErrors{
+		{Field: "name", Rule: "min", Message: "must be at least 2 characters"},
+		{Field: "email", Rule: "email", Message: "must be an email address"},
+		{Field: "age", Rule: "min", Message: "must be at least 18"},
+		{Field: "plan", Rule: "oneof", Message: "must be one of free pro"},
+		{Field: "tags[0]", Rule: "min", Message: "must be at least 3 characters"},
+		{Field: "address.street", Rule: "required", Message: "is required"},
+		{Field: "addresses[0].zip", Rule: "regexp", Message: "must match ^[0-9]+$"}}, "Errors describe each field")
+}
+
+func TestValidateOmitsEmptyOptionalFields(t *testing.T) {
+	r := validRegistration()
+	r.Email = ""
+
+	assert.Equal(t, Validate(&r), nil, "Error should be nil")
+}
+
+func TestValidateCustomValidator(t *testing.T) {
+	reset()
+	RegisterValidator("even", func(value reflect.Value, param string) bool {
+		return value.Int()%2 == 0
+	})
+	target := struct {
+		Count int `json:"count" validate:"even"`
+	}{Count: 3}
+
+	err := Validate(&target)
+
+	assert.Equal(t, err, ValidationErrors{{Field: "count", Rule: "even", Message: "must be even"}}, "Custom rule is applied")
+}
+
+func TestTypedHandlerValidationResponds422(t *testing.T) {
+	reset()
+	PostTyped("/registrations", func(req RequestEntity, r registration) (registration, error) {
+		return r, nil
+	})
+
+	recorder := requestTyped(http.MethodPost, "/registrations", "application/json", "application/json", `{"name":"Mario","age":12,"plan":"pro","address":{"street":"Mushroom Road","zip":"12345"}}`)
+
+	assert.Equal(t, recorder.Code, 422, "Response status code is 422")
+	assert.Equal(t, recorder.Body.String(), `{"type":"about:blank","title":"Unprocessable Entity","status":422,"detail":"request is invalid","instance":"/registrations","code":"validation_failed","details":[{"field":"age","rule":"min","message":"must be at least 18"}]}`, "Response body lists the invalid fields")
+}
+
+func TestTypedHandlerValidatesQuery(t *testing.T) {
+	reset()
+	GetTyped("/persons", func(req RequestEntity, query struct {
+		Page int `validate:"min=1"`
+	}) (Person, error) {
+		return Person{}, nil
+	})
+
+	recorder := requestTyped(http.MethodGet, "/persons?page=0", "", "application/xml", "")
+
+	assert.Equal(t, recorder.Code, 422, "Response status code is 422")
+	assert.Equal(t, strings.Contains(recorder.Body.String(), "<details><field>Page</field><rule>min</rule>"), true, "Response body is negotiated")
+}
+
+func TestValidateUnknownRuleFails(t *testing.T) {
+	reset()
+	target := struct {
+		Name string `validate:"requried"`
+	}{}
+
+	err := Validate(&target)
+
+	var validationErrors ValidationErrors
+
+	assert.NotEqual(t, err, nil, "Unknown rule is an error")
+	assert.Equal(t, errors.As(err, &validationErrors), false, "Unknown rule is not a validation error")
+	assert.Equal(t, strings.Contains(err.Error(), `unknown rule "requried"`), true, "Error names the rule")
+}
+
+func TestValidateParsesTagsOncePerType(t *testing.T) {
+	reset()
+	r := validRegistration()
+
+	Validate(&r)
+	first, _ := structRules.Load(reflect.TypeOf(r))
+	Validate(&r)
+	second, _ := structRules.Load(reflect.TypeOf(r))
+
+	assert.Equal(t, len(first.(validatedStruct).fields), 7, "Rules of all fields are cached")
+	assert.Equal(t, reflect.ValueOf(first.(validatedStruct).fields).Pointer(), reflect.ValueOf(second.(validatedStruct).fields).Pointer(), "Rules are parsed once")
+}
+
+func TestTypedHandlerUnknownRuleResponds500(t *testing.T) {
+	reset()
+	called := false
+	PostTyped("/registrations", func(req RequestEntity, r struct {
+		Name string `json:"name" validate:"requried"`
+	}) (Person, error) {
+		called = true
+		return Person{}, nil
+	})
+
+	recorder := requestTyped(http.MethodPost, "/registrations", "application/json", "application/json", `{}`)
+
+	assert.Equal(t, recorder.Code, 500, "Response status code is 500")
+	assert.Equal(t, called, false, "Handler is not called")
+}
+
+func TestValidationErrorsAreErrors(t *testing.T) {
+	target := struct {
+		Name string `validate:"required"`
+	}{}
+
+	var validationErrors ValidationErrors
+
+	assert.Equal(t, errors.As(Validate(&target), &validationErrors), true, "Error is ValidationErrors")
+	assert.Equal(t, validationErrors.Error(), "Name is required", "Error message names the field")
+}